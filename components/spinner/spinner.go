@@ -162,15 +162,15 @@ func (m Model) Tick() lib.Msg {
 	}
 }
 
+// tick waits one frame on the program's clock, see lib.WithClock.
 func (m Model) tick(id, tag int) lib.Cmd {
-	return func() lib.Msg {
-		time.Sleep(m.Spinner.FPS)
+	return lib.Tick(m.Spinner.FPS, func(t time.Time) lib.Msg {
 		return TickMsg{
-			Time: time.Now(),
+			Time: t,
 			ID:   id,
 			tag:  tag,
 		}
-	}
+	})
 }
 
 // Option is used to set options in New.
@@ -17,7 +17,7 @@ type Model struct {
 	// MouseWheelEnabled enables mouse wheel scrolling.
 	MouseWheelEnabled bool
 
-	// MouseWheelDelta is the number of lines to scroll per wheel event.
+	// MouseWheelDelta is the number of lines to scroll per wheel step.
 	MouseWheelDelta int
 
 	// YOffset is the vertical scroll position.
@@ -199,12 +199,18 @@ func (m Model) Update(msg lib.Msg) (Model, lib.Cmd) {
 			break
 		}
 
+		// High-resolution wheels and touchpads may report several steps
+		steps := msg.Steps
+		if steps < 1 {
+			steps = 1
+		}
+
 		switch msg.Button {
 		case lib.MouseButtonWheelUp:
-			m.ScrollUp(m.MouseWheelDelta)
+			m.ScrollUp(m.MouseWheelDelta * steps)
 
 		case lib.MouseButtonWheelDown:
-			m.ScrollDown(m.MouseWheelDelta)
+			m.ScrollDown(m.MouseWheelDelta * steps)
 		}
 
 	case lib.WindowSizeMsg:
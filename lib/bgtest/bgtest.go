@@ -0,0 +1,344 @@
+// Package bgtest runs BubbleGum models in tests without a window.
+//
+// A TestModel calls Init, Update and View like a Program does, executes
+// commands with a lib.CommandExecutor and parses every view with
+// lib.ParseANSI into the lib.TerminalGrid the window would show. Tests feed
+// it key, mouse and size messages, wait for the screen to reach a state,
+// and compare it with golden files:
+//
+//	tm := bgtest.NewTestModel(t, newModel(), bgtest.WithInitialTermSize(40, 10))
+//	tm.Type("hello")
+//	tm.Send(lib.KeyMsg{Type: lib.KeyEnter})
+//	tm.WaitFor(func(g *lib.TerminalGrid) bool {
+//		return strings.Contains(bgtest.PlainText(g), "Hello, hello!")
+//	})
+//	bgtest.RequireEqualScreen(t, tm.Grid())
+//
+// Run the tests with -update to write the golden files.
+package bgtest
+
+import (
+	"context"
+	"reflect"
+	"runtime/debug"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/neurlang/bubblegum/lib"
+)
+
+// libPath is the import path of the lib package.
+var libPath = reflect.TypeOf(lib.KeyMsg{}).PkgPath()
+
+// Option configures a TestModel.
+type Option func(*options)
+
+type options struct {
+	cols, rows int
+	clock      lib.Clock
+}
+
+// WithInitialTermSize sets the size of the screen in cells. The model
+// receives it as a WindowSizeMsg after Init. The default is 80x24.
+func WithInitialTermSize(cols, rows int) Option {
+	return func(o *options) {
+		o.cols, o.rows = cols, rows
+	}
+}
+
+// WithClock sets the clock of the command executor, so tests can advance
+// Tick and Every timers with a lib.FakeClock.
+func WithClock(clock lib.Clock) Option {
+	return func(o *options) {
+		o.clock = clock
+	}
+}
+
+// TestModel runs a model without a window.
+type TestModel struct {
+	tb   testing.TB
+	msgs chan lib.Msg
+	exec *lib.CommandExecutor
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu       sync.Mutex
+	model    lib.Model
+	grid     *lib.TerminalGrid
+	cols     int
+	rows     int
+	quitting bool
+}
+
+// NewTestModel starts m. It calls Init, executes its command and sends the
+// initial WindowSizeMsg. The model is stopped when the test ends.
+func NewTestModel(tb testing.TB, m lib.Model, opts ...Option) *TestModel {
+	tb.Helper()
+
+	o := options{cols: 80, rows: 24}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.cols <= 0 || o.rows <= 0 {
+		tb.Fatalf("bgtest: invalid terminal size %dx%d", o.cols, o.rows)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	tm := &TestModel{
+		tb:     tb,
+		msgs:   make(chan lib.Msg, 256),
+		ctx:    ctx,
+		cancel: cancel,
+		done:   make(chan struct{}),
+		model:  m,
+		cols:   o.cols,
+		rows:   o.rows,
+	}
+	tm.exec = lib.NewCommandExecutor(ctx, tm.msgs)
+	if o.clock != nil {
+		tm.exec.SetClock(o.clock)
+	}
+
+	tm.mu.Lock()
+	if tm.call("Init", func() { tm.exec.Execute(tm.model.Init()) }) &&
+		tm.update(lib.WindowSizeMsg{Width: o.cols, Height: o.rows}) {
+		tm.render()
+	}
+	tm.mu.Unlock()
+
+	go tm.loop()
+	tb.Cleanup(tm.stop)
+	return tm
+}
+
+// Send delivers a message to the model's Update, like Program.Send.
+func (tm *TestModel) Send(msg lib.Msg) {
+	select {
+	case tm.msgs <- msg:
+	case <-tm.ctx.Done():
+	}
+}
+
+// Type sends a KeyMsg for every rune of s.
+func (tm *TestModel) Type(s string) {
+	for _, r := range s {
+		tm.Send(lib.KeyMsg{Type: lib.KeyRunes, Runes: []rune{r}})
+	}
+}
+
+// Click sends a press and a release of the left button at cell x, y.
+func (tm *TestModel) Click(x, y int) {
+	tm.Send(lib.MouseMsg{X: x, Y: y, Type: lib.MousePress, Button: lib.MouseButtonLeft})
+	tm.Send(lib.MouseMsg{X: x, Y: y, Type: lib.MouseRelease, Button: lib.MouseButtonLeft})
+}
+
+// Resize changes the size of the screen and sends a WindowSizeMsg.
+func (tm *TestModel) Resize(cols, rows int) {
+	tm.Send(lib.WindowSizeMsg{Width: cols, Height: rows})
+}
+
+// Quit stops the model as if it had returned the lib.Quit command.
+func (tm *TestModel) Quit() {
+	tm.Send(lib.QuitMsg{})
+}
+
+// Grid returns a copy of the screen as of the last rendered view.
+func (tm *TestModel) Grid() *lib.TerminalGrid {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	return copyGrid(tm.grid)
+}
+
+// Model returns the current model.
+func (tm *TestModel) Model() lib.Model {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	return tm.model
+}
+
+// WaitOption configures WaitFor and FinalModel.
+type WaitOption func(*waitOptions)
+
+type waitOptions struct {
+	duration      time.Duration
+	checkInterval time.Duration
+}
+
+// WithDuration sets how long WaitFor and FinalModel wait. The default is
+// one second.
+func WithDuration(d time.Duration) WaitOption {
+	return func(o *waitOptions) {
+		o.duration = d
+	}
+}
+
+// WithCheckInterval sets how often WaitFor checks its condition. The
+// default is 10 milliseconds.
+func WithCheckInterval(d time.Duration) WaitOption {
+	return func(o *waitOptions) {
+		o.checkInterval = d
+	}
+}
+
+func newWaitOptions(opts []WaitOption) waitOptions {
+	o := waitOptions{duration: time.Second, checkInterval: 10 * time.Millisecond}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WaitFor waits until cond reports true for the screen, and fails the test
+// with the last screen if it does not in time.
+func (tm *TestModel) WaitFor(cond func(grid *lib.TerminalGrid) bool, opts ...WaitOption) {
+	tm.tb.Helper()
+
+	o := newWaitOptions(opts)
+	deadline := time.Now().Add(o.duration)
+	for {
+		grid := tm.Grid()
+		if cond(grid) {
+			return
+		}
+		if time.Now().After(deadline) {
+			tm.tb.Fatalf("bgtest: condition not met after %v, screen:\n%s", o.duration, PlainText(grid))
+		}
+		time.Sleep(o.checkInterval)
+	}
+}
+
+// FinalModel waits for the model to quit and returns it. It fails the test
+// if the model does not quit in time.
+func (tm *TestModel) FinalModel(opts ...WaitOption) lib.Model {
+	tm.tb.Helper()
+
+	o := newWaitOptions(opts)
+	select {
+	case <-tm.done:
+	case <-time.After(o.duration):
+		tm.tb.Fatalf("bgtest: model did not quit after %v", o.duration)
+	}
+	return tm.Model()
+}
+
+// FinalGrid waits for the model to quit and returns the last screen.
+func (tm *TestModel) FinalGrid(opts ...WaitOption) *lib.TerminalGrid {
+	tm.tb.Helper()
+	tm.FinalModel(opts...)
+	return tm.Grid()
+}
+
+// loop delivers the messages of Send and of commands to the model until it
+// quits, rendering the view after each batch of messages.
+func (tm *TestModel) loop() {
+	defer close(tm.done)
+
+	for {
+		var msg lib.Msg
+		select {
+		case msg = <-tm.msgs:
+		case <-tm.ctx.Done():
+			return
+		}
+
+		tm.mu.Lock()
+		running := tm.update(msg)
+		// Deliver what is already queued before rendering, like a frame
+		for running && len(tm.msgs) > 0 {
+			running = tm.update(<-tm.msgs)
+		}
+		// The view is rendered one last time when the model quits
+		if running || tm.quitting {
+			running = tm.render() && !tm.quitting
+		}
+		tm.mu.Unlock()
+
+		if !running {
+			tm.cancel()
+			return
+		}
+	}
+}
+
+// update delivers one message to the model and executes its command. It
+// reports false once the model quit or panicked. Must be called with tm.mu
+// held.
+func (tm *TestModel) update(msg lib.Msg) bool {
+	switch m := msg.(type) {
+	case nil:
+		return true
+	case lib.QuitMsg:
+		tm.quitting = true
+		return false
+	case lib.WindowSizeMsg:
+		tm.cols, tm.rows = m.Width, m.Height
+	case lib.MouseMsg:
+		if tm.grid != nil {
+			m.Zones = tm.grid.ZoneAt(m.X, m.Y)
+			msg = m
+		}
+	}
+
+	// Messages of unexported lib types are addressed to the window, such
+	// as a new title or mouse mode, and have no effect without one
+	if t := reflect.TypeOf(msg); t.PkgPath() == libPath && !isExported(t.Name()) {
+		return true
+	}
+
+	return tm.call("Update", func() {
+		var cmd lib.Cmd
+		tm.model, cmd = tm.model.Update(msg)
+		tm.exec.Execute(cmd)
+	})
+}
+
+// render parses the view into the screen grid. It reports false if View
+// panicked. Must be called with tm.mu held.
+func (tm *TestModel) render() bool {
+	return tm.call("View", func() {
+		tm.grid = lib.ParseANSI(tm.model.View(), tm.cols, tm.rows)
+	})
+}
+
+// call runs fn and reports a panic in the model's method as a test error.
+func (tm *TestModel) call(method string, fn func()) (ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			tm.tb.Errorf("bgtest: panic in %s(): %v\n%s", method, r, debug.Stack())
+			ok = false
+		}
+	}()
+	fn()
+	return true
+}
+
+// stop ends the model and waits for its commands.
+func (tm *TestModel) stop() {
+	tm.cancel()
+	<-tm.done
+	if err := tm.exec.ShutdownTimeout(time.Second); err != nil {
+		tm.tb.Logf("bgtest: %v", err)
+	}
+}
+
+// isExported reports whether a type name starts with an upper-case letter.
+func isExported(name string) bool {
+	return name != "" && name[0] >= 'A' && name[0] <= 'Z'
+}
+
+// copyGrid returns a deep copy of grid, or nil.
+func copyGrid(grid *lib.TerminalGrid) *lib.TerminalGrid {
+	if grid == nil {
+		return nil
+	}
+	c := *grid
+	c.Cells = make([][]lib.Cell, len(grid.Cells))
+	for y, row := range grid.Cells {
+		c.Cells[y] = append([]lib.Cell(nil), row...)
+	}
+	c.Zones = append([]lib.Zone(nil), grid.Zones...)
+	return &c
+}
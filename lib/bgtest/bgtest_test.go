@@ -0,0 +1,126 @@
+package bgtest
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/neurlang/bubblegum/lib"
+)
+
+// greeter asks for a name and greets it on Enter.
+type greeter struct {
+	input    string
+	name     string
+	width    int
+	ticks    int
+	quitting bool
+}
+
+type tickMsg time.Time
+
+func (m greeter) Init() lib.Cmd { return nil }
+
+func (m greeter) Update(msg lib.Msg) (lib.Model, lib.Cmd) {
+	switch msg := msg.(type) {
+	case lib.WindowSizeMsg:
+		m.width = msg.Width
+	case lib.KeyMsg:
+		switch msg.Type {
+		case lib.KeyRunes:
+			m.input += string(msg.Runes)
+		case lib.KeyEnter:
+			m.name, m.input = m.input, ""
+			return m, lib.Tick(time.Second, func(t time.Time) lib.Msg { return tickMsg(t) })
+		case lib.KeyEsc:
+			m.quitting = true
+			return m, lib.Quit
+		}
+	case tickMsg:
+		m.ticks++
+	}
+	return m, nil
+}
+
+func (m greeter) View() string {
+	var b strings.Builder
+	b.WriteString("Name: " + m.input + "\n")
+	if m.name != "" {
+		b.WriteString("\x1b[1;31mHello, " + m.name + "!\x1b[0m\n")
+	}
+	if m.ticks > 0 {
+		b.WriteString("tick\n")
+	}
+	if m.quitting {
+		b.WriteString("Bye\n")
+	}
+	return b.String()
+}
+
+func TestTestModel_Golden(t *testing.T) {
+	tm := NewTestModel(t, greeter{}, WithInitialTermSize(20, 4))
+
+	tm.Type("gopher")
+	tm.Send(lib.KeyMsg{Type: lib.KeyEnter})
+	tm.WaitFor(func(g *lib.TerminalGrid) bool {
+		return strings.Contains(PlainText(g), "Hello, gopher!")
+	})
+
+	RequireEqualScreen(t, tm.Grid())
+	RequireEqualStyledScreen(t, tm.Grid())
+}
+
+func TestTestModel_FakeClock(t *testing.T) {
+	clock := lib.NewFakeClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	tm := NewTestModel(t, greeter{}, WithClock(clock))
+
+	tm.Send(lib.KeyMsg{Type: lib.KeyEnter})
+	clock.BlockUntil(1)
+	if m := tm.Model().(greeter); m.ticks != 0 {
+		t.Fatalf("ticks = %d before the clock advanced", m.ticks)
+	}
+
+	clock.Advance(time.Second)
+	tm.WaitFor(func(g *lib.TerminalGrid) bool {
+		return strings.Contains(PlainText(g), "tick")
+	})
+}
+
+func TestTestModel_FinalModel(t *testing.T) {
+	tm := NewTestModel(t, greeter{}, WithInitialTermSize(30, 5))
+
+	tm.Type("hi")
+	tm.Send(lib.KeyMsg{Type: lib.KeyEsc})
+
+	m := tm.FinalModel(WithDuration(time.Second)).(greeter)
+	if m.input != "hi" || m.width != 30 {
+		t.Errorf("final model = %+v, want input %q and width 30", m, "hi")
+	}
+	if got := PlainText(tm.FinalGrid()); !strings.Contains(got, "Bye") {
+		t.Errorf("final screen does not show the last view:\n%s", got)
+	}
+}
+
+func TestPlainText(t *testing.T) {
+	grid := lib.ParseANSI("ab  \n\x1b[4mc\x1b[0m", 5, 3)
+
+	if got, want := PlainText(grid), "ab\nc\n\n"; got != want {
+		t.Errorf("PlainText = %q, want %q", got, want)
+	}
+	if got, want := StyledText(grid), "ab\nc\n  0-0 underline\n\n"; got != want {
+		t.Errorf("StyledText = %q, want %q", got, want)
+	}
+	if PlainText(nil) != "" {
+		t.Error("PlainText(nil) is not empty")
+	}
+}
+
+func TestTestModel_GoldenImage(t *testing.T) {
+	tm := NewTestModel(t, greeter{name: "gopher"}, WithInitialTermSize(16, 2))
+
+	img, err := lib.RenderImage(tm.Grid(), lib.DefaultTheme(), nil)
+	if err != nil {
+		t.Fatalf("RenderImage failed: %v", err)
+	}
+	RequireEqualImage(t, img, 0)
+}
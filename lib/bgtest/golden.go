@@ -0,0 +1,211 @@
+package bgtest
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/neurlang/bubblegum/lib"
+)
+
+// update makes the golden file helpers write the golden files instead of
+// comparing against them.
+var update = flag.Bool("update", false, "update the golden files of bgtest")
+
+// PlainText returns the characters of the grid, one line per row, with
+// trailing spaces removed.
+func PlainText(grid *lib.TerminalGrid) string {
+	if grid == nil {
+		return ""
+	}
+
+	var b strings.Builder
+	for _, row := range grid.Cells {
+		line := make([]rune, 0, len(row))
+		for _, cell := range row {
+			line = append(line, cell.Rune)
+		}
+		b.WriteString(strings.TrimRight(string(line), " "))
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// StyledText returns the characters of the grid like PlainText, with the
+// styled runs of each row listed below it. A run is written as its first
+// and last column followed by the style of its cells, for example
+// "  3-7 fg=#ff0000 bold". Cells with default colors and no attributes
+// are not listed.
+func StyledText(grid *lib.TerminalGrid) string {
+	if grid == nil {
+		return ""
+	}
+
+	var b strings.Builder
+	lines := strings.Split(strings.TrimSuffix(PlainText(grid), "\n"), "\n")
+	for y, row := range grid.Cells {
+		b.WriteString(lines[y])
+		b.WriteByte('\n')
+
+		for start := 0; start < len(row); {
+			style := cellStyle(row[start])
+			end := start
+			for end+1 < len(row) && cellStyle(row[end+1]) == style {
+				end++
+			}
+			if style != "" {
+				fmt.Fprintf(&b, "  %d-%d%s\n", start, end, style)
+			}
+			start = end + 1
+		}
+	}
+	return b.String()
+}
+
+// cellStyle describes the colors and attributes of a cell, or returns an
+// empty string for the default style.
+func cellStyle(c lib.Cell) string {
+	var b strings.Builder
+	if !c.FgColor.IsDefault {
+		fmt.Fprintf(&b, " fg=#%02x%02x%02x", c.FgColor.R, c.FgColor.G, c.FgColor.B)
+	}
+	if !c.BgColor.IsDefault {
+		fmt.Fprintf(&b, " bg=#%02x%02x%02x", c.BgColor.R, c.BgColor.G, c.BgColor.B)
+	}
+	for _, attr := range []struct {
+		on   bool
+		name string
+	}{
+		{c.Bold, "bold"},
+		{c.Italic, "italic"},
+		{c.Underline, "underline"},
+		{c.Strikethrough, "strikethrough"},
+	} {
+		if attr.on {
+			b.WriteString(" " + attr.name)
+		}
+	}
+	return b.String()
+}
+
+// RequireEqualScreen compares the text of the grid with the golden file
+// testdata/<test name>.golden.
+func RequireEqualScreen(tb testing.TB, grid *lib.TerminalGrid) {
+	tb.Helper()
+	RequireEqualGolden(tb, goldenPath(tb, ".golden"), []byte(PlainText(grid)))
+}
+
+// RequireEqualStyledScreen compares the text and styles of the grid with the
+// golden file testdata/<test name>.styled.golden.
+func RequireEqualStyledScreen(tb testing.TB, grid *lib.TerminalGrid) {
+	tb.Helper()
+	RequireEqualGolden(tb, goldenPath(tb, ".styled.golden"), []byte(StyledText(grid)))
+}
+
+// RequireEqualGolden compares got with the golden file at path and fails
+// the test if they differ. With the -update flag it writes got to the file
+// instead.
+func RequireEqualGolden(tb testing.TB, path string, got []byte) {
+	tb.Helper()
+
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			tb.Fatalf("bgtest: %v", err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			tb.Fatalf("bgtest: %v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		tb.Fatalf("bgtest: %v (run the test with -update to create it)", err)
+	}
+	if !bytes.Equal(got, want) {
+		tb.Fatalf("bgtest: output does not match %s (run the test with -update to accept it)\n%s",
+			path, lineDiff(string(want), string(got)))
+	}
+}
+
+// RequireEqualImage compares an image with the golden PNG file
+// testdata/<test name>.png. Color channels may differ by tolerance. On a
+// mismatch the image and a diff image are written to a temporary directory
+// for inspection. With the -update flag it writes the golden file instead.
+// Render a screen with lib.RenderImage.
+func RequireEqualImage(tb testing.TB, img image.Image, tolerance uint8) {
+	tb.Helper()
+	path := goldenPath(tb, ".png")
+
+	if *update {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			tb.Fatalf("bgtest: %v", err)
+		}
+		RequireEqualGolden(tb, path, buf.Bytes())
+		return
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		tb.Fatalf("bgtest: %v (run the test with -update to create it)", err)
+	}
+	want, err := png.Decode(f)
+	f.Close()
+	if err != nil {
+		tb.Fatalf("bgtest: failed to decode %s: %v", path, err)
+	}
+
+	diff := lib.DiffImages(want, img, tolerance)
+	if diff.Equal() {
+		return
+	}
+
+	dir, err := os.MkdirTemp("", "bgtest-")
+	if err != nil {
+		tb.Fatalf("bgtest: %v", err)
+	}
+	gotPath := filepath.Join(dir, "got.png")
+	diffPath := filepath.Join(dir, "diff.png")
+	if err := lib.WritePNG(gotPath, img); err != nil {
+		tb.Logf("bgtest: %v", err)
+	}
+	if err := lib.WritePNG(diffPath, diff.Image); err != nil {
+		tb.Logf("bgtest: %v", err)
+	}
+	tb.Fatalf("bgtest: image does not match %s: %d pixels differ by up to %d (tolerance %d)\n  got:  %s\n  diff: %s",
+		path, diff.Pixels, diff.MaxDelta, tolerance, gotPath, diffPath)
+}
+
+// goldenPath returns the golden file of the running test with the given
+// suffix. Subtest names become directories.
+func goldenPath(tb testing.TB, suffix string) string {
+	return filepath.Join("testdata", filepath.FromSlash(tb.Name())+suffix)
+}
+
+// lineDiff lists the lines that differ between want and got.
+func lineDiff(want, got string) string {
+	wantLines := strings.Split(want, "\n")
+	gotLines := strings.Split(got, "\n")
+
+	var b strings.Builder
+	for i := 0; i < max(len(wantLines), len(gotLines)); i++ {
+		var w, g string
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if w != g {
+			fmt.Fprintf(&b, "line %d:\n  want %q\n  got  %q\n", i+1, w, g)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,140 @@
+package lib
+
+import (
+	"io"
+	"os"
+
+	"github.com/neurlang/wayland/wl"
+	"github.com/neurlang/wayland/wlclient"
+)
+
+// clipboardMimeTypes are the text formats offered when text is copied.
+var clipboardMimeTypes = []string{
+	"UTF8_STRING",
+	"text/plain;charset=utf-8",
+	"text/plain;charset=UTF-8",
+	"text/plain",
+}
+
+// clipboardSource serves copied text to other applications that paste it.
+type clipboardSource struct {
+	src  *wl.DataSource
+	text string
+}
+
+// HandleDataSourceSend writes the copied text to the requesting application.
+func (c *clipboardSource) HandleDataSourceSend(ev wl.DataSourceSendEvent) {
+	writeSelection(ev.Fd, c.text)
+}
+
+// HandleDataSourceTarget implements wlclient.DataSourceListener.
+func (c *clipboardSource) HandleDataSourceTarget(ev wl.DataSourceTargetEvent) {}
+
+// HandleDataSourceCancelled destroys the source once another offer replaced
+// it on the clipboard.
+func (c *clipboardSource) HandleDataSourceCancelled(ev wl.DataSourceCancelledEvent) {
+	c.destroy()
+}
+
+// HandleDataSourceDndDropPerformed implements wlclient.DataSourceListener.
+func (c *clipboardSource) HandleDataSourceDndDropPerformed(ev wl.DataSourceDndDropPerformedEvent) {}
+
+// HandleDataSourceDndFinished implements wlclient.DataSourceListener.
+func (c *clipboardSource) HandleDataSourceDndFinished(ev wl.DataSourceDndFinishedEvent) {}
+
+// HandleDataSourceAction implements wlclient.DataSourceListener.
+func (c *clipboardSource) HandleDataSourceAction(ev wl.DataSourceActionEvent) {}
+
+// destroy stops serving the text and releases the source. It can be called
+// more than once.
+func (c *clipboardSource) destroy() {
+	if c.src == nil {
+		return
+	}
+	wlclient.DataSourceRemoveListener(c.src, c)
+	_ = c.src.Destroy()
+	c.src.Unregister()
+	c.src = nil
+}
+
+// writeSelection writes copied text to the pipe of an application pasting
+// it, and closes the pipe.
+func writeSelection(fd uintptr, text string) {
+	f := os.NewFile(fd, "selection")
+	if f == nil {
+		return
+	}
+	defer f.Close()
+
+	if _, err := io.WriteString(f, text); err != nil {
+		Warn("Failed to send selection data: %v", err)
+	}
+}
+
+// copyToClipboard offers text on the clipboard and the primary selection,
+// replacing any previous offer. Embedded programs copy through the display
+// of the host window. Must be called from the display thread.
+func (p *Program) copyToClipboard(text string) {
+	display := p.display
+	if p.embedded {
+		display = p.hostDisplay
+	}
+
+	dropTargets.Lock()
+	dev := dropTargets.devices[display]
+	dropTargets.Unlock()
+	if display == nil || dev == nil {
+		Warn("No data device, not copying %d bytes to the clipboard", len(text))
+		return
+	}
+
+	dev.setSelection(text, display.GetSerial())
+}
+
+// setSelection offers text on the clipboard and, if the compositor supports
+// it, on the primary selection, and destroys the sources of earlier offers.
+func (d *dropDevice) setSelection(text string, serial uint32) {
+	if d.clipboard != nil {
+		d.clipboard.destroy()
+		d.clipboard = nil
+	}
+	if d.primary != nil {
+		d.primary.destroy()
+		d.primary = nil
+	}
+
+	if d.device == nil {
+		Warn("The compositor has no data device, not copying %d bytes to the clipboard", len(text))
+		return
+	}
+	src, err := d.manager.CreateDataSource()
+	if err != nil {
+		Warn("Failed to create clipboard data source: %v", err)
+		src.Unregister()
+		return
+	}
+	d.clipboard = &clipboardSource{src: src, text: text}
+	wlclient.DataSourceAddListener(src, d.clipboard)
+	for _, mime := range clipboardMimeTypes {
+		_ = src.Offer(mime)
+	}
+	_ = d.device.SetSelection(src, serial)
+	Debug("Copied %d bytes to the clipboard", len(text))
+
+	if d.primaryDevice == nil {
+		Debug("The compositor has no primary selection")
+		return
+	}
+	primary, err := d.primaryManager.createSource()
+	if err != nil {
+		Warn("Failed to create primary selection source: %v", err)
+		primary.Unregister()
+		return
+	}
+	primary.text = text
+	for _, mime := range clipboardMimeTypes {
+		_ = primary.offer(mime)
+	}
+	_ = d.primaryDevice.setSelection(primary, serial)
+	d.primary = primary
+}
@@ -0,0 +1,76 @@
+package lib
+
+import (
+	"io"
+	"os"
+	"testing"
+
+	"github.com/neurlang/wayland/window"
+	"github.com/neurlang/wayland/wl"
+)
+
+// TestCopyToClipboard_PrimarySelection tests that an embedded program copies
+// through the display of its host window to both the clipboard and the
+// primary selection, serves the text, and destroys replaced sources.
+func TestCopyToClipboard_PrimarySelection(t *testing.T) {
+	display, server := newFakeCompositor(t)
+	p, err := NewEmbeddedProgram(panelModel{}, &window.Window{Display: display}, window.Rectangle{Width: 80, Height: 40})
+	if err != nil {
+		t.Fatalf("NewEmbeddedProgram() error = %v", err)
+	}
+	defer p.Detach()
+
+	dropTargets.Lock()
+	dev := dropTargets.devices[display]
+	dropTargets.Unlock()
+	if dev == nil {
+		t.Fatal("embedded program has no data device")
+	}
+	server.expect(display.Display, 1)
+	dev.HandleRegistryGlobal(wl.RegistryGlobalEvent{Name: 1, Interface: "wl_seat", Version: 7})
+	dev.HandleRegistryGlobal(wl.RegistryGlobalEvent{Name: 2, Interface: "wl_data_device_manager", Version: 3})
+	dev.HandleRegistryGlobal(wl.RegistryGlobalEvent{Name: 3, Interface: primarySelectionInterface, Version: 1})
+	server.skip(5)
+
+	p.copyToClipboard("hello")
+	server.expect(dev.manager, 0)
+	server.skip(len(clipboardMimeTypes))
+	server.expect(dev.device, 1)
+	server.expect(dev.primaryManager, 0)
+	server.skip(len(clipboardMimeTypes))
+	server.expect(dev.primaryDevice, 0)
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	server.send(dev.primary, 0, wlString("text/plain"), int(w.Fd()))
+	w.Close()
+	dispatch(t, display)
+	if data, _ := io.ReadAll(r); string(data) != "hello" {
+		t.Errorf("primary selection served %q, want %q", data, "hello")
+	}
+
+	clipboard, primary := dev.clipboard.src, dev.primary
+	p.copyToClipboard("world")
+	server.expect(clipboard, 1)
+	server.expect(primary, 1)
+	server.skip(2 + 2*len(clipboardMimeTypes) + 2)
+
+	// Another application took over the primary selection
+	primary = dev.primary
+	server.send(primary, 1, nil)
+	dispatch(t, display)
+	server.expect(primary, 1)
+
+	clipboard = dev.clipboard.src
+	p.Detach()
+	dropTargets.Lock()
+	_, kept := dropTargets.devices[display]
+	dropTargets.Unlock()
+	if kept {
+		t.Error("detached program did not release the data device")
+	}
+	server.expect(clipboard, 1)
+}
@@ -0,0 +1,11 @@
+package lib
+
+import "github.com/neurlang/wayland/window"
+
+// copyToClipboard writes text to the clipboard. Windows has no primary
+// selection. The backend writes the text without using the display or the
+// input device, so embedded programs copy the same way.
+func (p *Program) copyToClipboard(text string) {
+	p.input.DeviceSetSelection(&window.DataSource{CopyBuffer: text}, 0)
+	Debug("Copied %d bytes to the clipboard", len(text))
+}
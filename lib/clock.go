@@ -0,0 +1,251 @@
+package lib
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock is the source of time for Tick, Every, the other timers of the
+// command executor, long presses and kinetic scrolling. Tests can replace
+// it with a FakeClock to run timers and animations without waiting for
+// them.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// After waits for the duration to elapse and then sends the current
+	// time on the returned channel.
+	After(d time.Duration) <-chan time.Time
+
+	// NewTimer returns a Timer that sends the current time once d has
+	// elapsed. Unlike After, the timer can be stopped when its receiver
+	// stops waiting.
+	NewTimer(d time.Duration) Timer
+
+	// NewTicker returns a Ticker that sends the current time every d.
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker delivers ticks at intervals, like time.Ticker.
+type Ticker interface {
+	// C returns the channel on which the ticks are delivered.
+	C() <-chan time.Time
+
+	// Stop turns off the ticker. No more ticks are sent after it returns.
+	Stop()
+}
+
+// Timer delivers a single tick after a duration, like time.Timer.
+type Timer interface {
+	// C returns the channel on which the tick is delivered.
+	C() <-chan time.Time
+
+	// Stop prevents the timer from firing. It reports whether the timer
+	// was stopped before it fired.
+	Stop() bool
+}
+
+// RealClock returns the Clock that follows the system time. It is the
+// default clock of a program.
+func RealClock() Clock {
+	return realClock{}
+}
+
+// realClock implements Clock with the time package.
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (realClock) NewTimer(d time.Duration) Timer         { return realTimer{time.NewTimer(d)} }
+func (realClock) NewTicker(d time.Duration) Ticker       { return realTicker{time.NewTicker(d)} }
+
+// realTimer implements Timer with a time.Timer.
+type realTimer struct {
+	timer *time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time { return t.timer.C }
+func (t realTimer) Stop() bool          { return t.timer.Stop() }
+
+// realTicker implements Ticker with a time.Ticker.
+type realTicker struct {
+	ticker *time.Ticker
+}
+
+func (t realTicker) C() <-chan time.Time { return t.ticker.C }
+func (t realTicker) Stop()               { t.ticker.Stop() }
+
+// FakeClock is a Clock whose time only moves when Advance is called. Timers
+// and tickers fire during Advance when their time is reached. Like
+// time.Ticker, a ticker drops ticks its receiver is not ready for, so
+// advance one interval at a time to observe every tick. Stopped timers and
+// tickers stop waiting for the clock; a channel returned by After waits
+// until it fires.
+type FakeClock struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	waiters []*fakeWaiter
+}
+
+// fakeWaiter is a pending timer or ticker of a FakeClock.
+type fakeWaiter struct {
+	when   time.Time
+	period time.Duration // zero for one-shot timers
+	ch     chan time.Time
+	clock  *FakeClock
+}
+
+// NewFakeClock returns a FakeClock set to start.
+func NewFakeClock(start time.Time) *FakeClock {
+	c := &FakeClock{now: start}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+// Now returns the time of the clock.
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// After returns a channel that receives the time once the clock has been
+// advanced by d.
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.add(&fakeWaiter{when: c.now.Add(d), ch: ch, clock: c})
+	return ch
+}
+
+// NewTimer returns a Timer that fires once the clock has been advanced
+// by d.
+func (c *FakeClock) NewTimer(d time.Duration) Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	w := &fakeWaiter{when: c.now.Add(d), ch: make(chan time.Time, 1), clock: c}
+	if d <= 0 {
+		w.ch <- c.now
+		return fakeTimer{w}
+	}
+	c.add(w)
+	return fakeTimer{w}
+}
+
+// NewTicker returns a Ticker that ticks every time the clock has been
+// advanced by d.
+func (c *FakeClock) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("non-positive interval for NewTicker")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	w := &fakeWaiter{when: c.now.Add(d), period: d, ch: make(chan time.Time, 1), clock: c}
+	c.add(w)
+	return w
+}
+
+// Advance moves the clock forward by d and fires the timers and tickers
+// that are due, in order of their time.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	end := c.now.Add(d)
+	for len(c.waiters) > 0 && !c.waiters[0].when.After(end) {
+		w := c.waiters[0]
+		c.waiters = c.waiters[1:]
+		c.now = w.when
+
+		select {
+		case w.ch <- w.when:
+		default:
+			// Dropped, like a slow receiver of a time.Ticker
+		}
+		if w.period > 0 {
+			w.when = w.when.Add(w.period)
+			c.add(w)
+		}
+	}
+	c.now = end
+}
+
+// Waiters returns the number of timers and tickers waiting for the clock.
+func (c *FakeClock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.waiters)
+}
+
+// BlockUntil blocks until at least n timers and tickers are waiting for the
+// clock. Tests call it before Advance to make sure a command has started
+// its timer.
+func (c *FakeClock) BlockUntil(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.waiters) < n {
+		c.cond.Wait()
+	}
+}
+
+// add inserts a waiter, keeping them sorted by time. Must be called with
+// c.mu held.
+func (c *FakeClock) add(w *fakeWaiter) {
+	i := sort.Search(len(c.waiters), func(i int) bool {
+		return c.waiters[i].when.After(w.when)
+	})
+	c.waiters = append(c.waiters, nil)
+	copy(c.waiters[i+1:], c.waiters[i:])
+	c.waiters[i] = w
+	c.cond.Broadcast()
+}
+
+// C returns the channel of a fake ticker.
+func (w *fakeWaiter) C() <-chan time.Time {
+	return w.ch
+}
+
+// Stop removes a fake ticker from its clock.
+func (w *fakeWaiter) Stop() {
+	w.clock.remove(w)
+}
+
+// remove takes a waiter off the clock and reports whether it was waiting.
+func (c *FakeClock) remove(w *fakeWaiter) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, other := range c.waiters {
+		if other == w {
+			c.waiters = append(c.waiters[:i], c.waiters[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// fakeTimer is a one-shot waiter of a FakeClock.
+type fakeTimer struct {
+	w *fakeWaiter
+}
+
+// C returns the channel of a fake timer.
+func (t fakeTimer) C() <-chan time.Time {
+	return t.w.ch
+}
+
+// Stop removes a fake timer from its clock.
+func (t fakeTimer) Stop() bool {
+	return t.w.clock.remove(t.w)
+}
@@ -0,0 +1,235 @@
+package lib
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+var fakeStart = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func TestFakeClock_After(t *testing.T) {
+	c := NewFakeClock(fakeStart)
+
+	ch := c.After(time.Second)
+	c.Advance(999 * time.Millisecond)
+	select {
+	case <-ch:
+		t.Fatal("After fired before its time")
+	default:
+	}
+
+	c.Advance(time.Millisecond)
+	select {
+	case got := <-ch:
+		if want := fakeStart.Add(time.Second); !got.Equal(want) {
+			t.Errorf("After sent %v, want %v", got, want)
+		}
+	default:
+		t.Fatal("After did not fire when its time was reached")
+	}
+
+	if got := c.Now(); !got.Equal(fakeStart.Add(time.Second)) {
+		t.Errorf("Now = %v, want %v", got, fakeStart.Add(time.Second))
+	}
+	if c.Waiters() != 0 {
+		t.Errorf("Waiters = %d, want 0", c.Waiters())
+	}
+
+	select {
+	case <-c.After(0):
+	default:
+		t.Error("After(0) did not fire immediately")
+	}
+}
+
+func TestFakeClock_Ticker(t *testing.T) {
+	c := NewFakeClock(fakeStart)
+	ticker := c.NewTicker(100 * time.Millisecond)
+
+	for i := 1; i <= 3; i++ {
+		c.Advance(100 * time.Millisecond)
+		select {
+		case got := <-ticker.C():
+			if want := fakeStart.Add(time.Duration(i) * 100 * time.Millisecond); !got.Equal(want) {
+				t.Errorf("tick %d = %v, want %v", i, got, want)
+			}
+		default:
+			t.Fatalf("tick %d not delivered", i)
+		}
+	}
+
+	ticker.Stop()
+	c.Advance(time.Second)
+	select {
+	case <-ticker.C():
+		t.Error("Ticker ticked after Stop")
+	default:
+	}
+}
+
+func TestFakeClock_TimerStop(t *testing.T) {
+	c := NewFakeClock(fakeStart)
+	timer := c.NewTimer(time.Second)
+	if c.Waiters() != 1 {
+		t.Fatalf("Waiters = %d, want 1", c.Waiters())
+	}
+
+	if !timer.Stop() {
+		t.Error("Stop of a pending timer returned false")
+	}
+	if c.Waiters() != 0 {
+		t.Errorf("Waiters = %d after Stop, want 0", c.Waiters())
+	}
+	if timer.Stop() {
+		t.Error("second Stop returned true")
+	}
+
+	c.Advance(time.Second)
+	select {
+	case <-timer.C():
+		t.Error("Timer fired after Stop")
+	default:
+	}
+}
+
+func TestFakeClock_AdvanceOrder(t *testing.T) {
+	c := NewFakeClock(fakeStart)
+	late := c.After(300 * time.Millisecond)
+	early := c.After(100 * time.Millisecond)
+
+	c.Advance(time.Second)
+
+	if got := <-early; !got.Equal(fakeStart.Add(100 * time.Millisecond)) {
+		t.Errorf("early fired at %v", got)
+	}
+	if got := <-late; !got.Equal(fakeStart.Add(300 * time.Millisecond)) {
+		t.Errorf("late fired at %v", got)
+	}
+}
+
+// TestTick_FakeClock tests that Tick waits on the executor's clock.
+func TestTick_FakeClock(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	clock := NewFakeClock(fakeStart)
+	msgChan := make(chan Msg, 10)
+	executor := NewCommandExecutor(ctx, msgChan)
+	executor.SetClock(clock)
+	defer executor.Shutdown()
+
+	executor.Execute(Tick(time.Hour, func(tm time.Time) Msg {
+		return tm
+	}))
+	clock.BlockUntil(1)
+
+	select {
+	case msg := <-msgChan:
+		t.Fatalf("Tick fired before the clock advanced: %v", msg)
+	default:
+	}
+
+	clock.Advance(time.Hour)
+	select {
+	case msg := <-msgChan:
+		if want := fakeStart.Add(time.Hour); msg != want {
+			t.Errorf("Tick sent %v, want %v", msg, want)
+		}
+	case <-time.After(1 * time.Second):
+		t.Fatal("Timeout waiting for tick message")
+	}
+}
+
+// TestEvery_FakeClock tests that Every ticks on the executor's clock.
+func TestEvery_FakeClock(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	clock := NewFakeClock(fakeStart)
+	msgChan := make(chan Msg, 10)
+	executor := NewCommandExecutor(ctx, msgChan)
+	executor.SetClock(clock)
+	defer executor.Shutdown()
+
+	executor.Execute(Every(time.Minute, func(tm time.Time) Msg {
+		return tm
+	}))
+	clock.BlockUntil(1)
+
+	for i := 1; i <= 3; i++ {
+		clock.Advance(time.Minute)
+		select {
+		case msg := <-msgChan:
+			if want := fakeStart.Add(time.Duration(i) * time.Minute); msg != want {
+				t.Errorf("tick %d = %v, want %v", i, msg, want)
+			}
+		case <-time.After(1 * time.Second):
+			t.Fatalf("Timeout waiting for tick %d", i)
+		}
+	}
+}
+
+// TestTick_Shutdown tests that shutdown does not wait for pending ticks.
+func TestTick_Shutdown(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	clock := NewFakeClock(fakeStart)
+	executor := NewCommandExecutor(ctx, make(chan Msg, 1))
+	executor.SetClock(clock)
+
+	executor.Execute(Tick(time.Hour, func(tm time.Time) Msg { return tm }))
+	clock.BlockUntil(1)
+
+	if err := executor.ShutdownTimeout(time.Second); err != nil {
+		t.Errorf("Expected pending tick to be cancelled on shutdown, got %v", err)
+	}
+	if n := clock.Waiters(); n != 0 {
+		t.Errorf("Waiters = %d after the tick was cancelled, want 0", n)
+	}
+}
+
+// TestProgram_LongPressFollowsClock tests that the long-press timer waits
+// on the program's clock.
+func TestProgram_LongPressFollowsClock(t *testing.T) {
+	clock := NewFakeClock(fakeStart)
+	p := NewProgram(nil, WithGestures(), WithClock(clock))
+	defer p.cancel()
+	renderer, err := NewRenderer(RendererOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create renderer: %v", err)
+	}
+	p.renderer = renderer
+
+	p.TouchDown(nil, nil, 0, 0, 1, 10, 10)
+	<-p.msgChan // TouchMsg
+	clock.BlockUntil(1)
+
+	select {
+	case msg := <-p.msgChan:
+		t.Fatalf("long press sent before the clock advanced: %v", msg)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	clock.Advance(longPressDelay)
+	select {
+	case msg := <-p.msgChan:
+		if g, ok := msg.(GestureMsg); !ok || g.Type != GestureLongPress {
+			t.Errorf("message = %v, want a long press", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for the long press")
+	}
+}
+
+func TestProgram_WithClock(t *testing.T) {
+	clock := NewFakeClock(fakeStart)
+	p := NewProgram(nil, WithClock(clock))
+	if p.options.Clock != clock {
+		t.Error("WithClock did not set the clock")
+	}
+	if err := NewProgram(nil, WithClock(nil)).validateOptions(); err == nil {
+		t.Error("Expected a nil clock to be rejected")
+	}
+}
@@ -3,6 +3,10 @@ package lib
 import (
 	"context"
 	"fmt"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 )
@@ -10,11 +14,33 @@ import (
 // Quit is a command that signals the program to exit.
 // This matches Bubble Tea's Quit command for compatibility.
 func Quit() Msg {
-	return quitMsg{}
+	return QuitMsg{}
 }
 
-// quitMsg is the internal message type for quit signals.
-type quitMsg struct{}
+// EnableMouseCellMotion is a command that enables mouse clicks, wheel events
+// and motion while a button is held (dragging).
+// This matches Bubble Tea's EnableMouseCellMotion command for compatibility.
+func EnableMouseCellMotion() Msg {
+	return setMouseModeMsg{mode: MouseModeCellMotion}
+}
+
+// EnableMouseAllMotion is a command that enables mouse clicks, wheel events
+// and all motion, including motion without a button held (hovering).
+// This matches Bubble Tea's EnableMouseAllMotion command for compatibility.
+func EnableMouseAllMotion() Msg {
+	return setMouseModeMsg{mode: MouseModeAllMotion}
+}
+
+// DisableMouse is a command that stops the delivery of all mouse events.
+// This matches Bubble Tea's DisableMouse command for compatibility.
+func DisableMouse() Msg {
+	return setMouseModeMsg{mode: MouseModeNone}
+}
+
+// setMouseModeMsg is the internal message type for changing the mouse mode.
+type setMouseModeMsg struct {
+	mode MouseMode
+}
 
 // Batch executes multiple commands concurrently and collects their messages.
 // This matches Bubble Tea's Batch command for compatibility.
@@ -29,20 +55,56 @@ type batchMsg struct {
 	cmds []Cmd
 }
 
+// Sequence runs commands one after another, starting each command after the
+// previous one has delivered its message, so Update receives the messages in
+// order. A nested Batch runs its commands concurrently and the sequence
+// continues when all of them are done.
+// This matches Bubble Tea's Sequence command for compatibility.
+func Sequence(cmds ...Cmd) Cmd {
+	return func() Msg {
+		return sequenceMsg{cmds: cmds}
+	}
+}
+
+// sequenceMsg is the internal message type for sequential command execution.
+type sequenceMsg struct {
+	cmds []Cmd
+}
+
 // Tick creates a command that waits for the specified duration and then sends a message.
+// The wait follows the clock of the program, see WithClock.
 // This matches Bubble Tea's Tick command for compatibility.
+//
+// The Program or CommandExecutor running the command does the waiting.
+// Unlike Bubble Tea, calling the returned Cmd directly does not sleep: it
+// returns at once with an internal message that only they can run.
 func Tick(d time.Duration, fn func(time.Time) Msg) Cmd {
 	return func() Msg {
-		time.Sleep(d)
-		return fn(time.Now())
+		return tickMsg{duration: d, fn: fn}
 	}
 }
 
-// Every creates a command that sends messages at regular intervals.
-// The returned function can be called to cancel the timer.
+// tickMsg is the internal message type for one-shot timer commands.
+type tickMsg struct {
+	duration time.Duration
+	fn       func(time.Time) Msg
+}
+
+// Every creates a command that sends messages at regular intervals until
+// the program quits. Use EveryWithID for a timer that can be stopped.
+// Like Tick, the command only runs in a Program or CommandExecutor.
 func Every(d time.Duration, fn func(time.Time) Msg) Cmd {
+	return EveryWithID("", d, fn)
+}
+
+// EveryWithID is like Every, and the timer can be stopped with StopTimer(id).
+// Starting a timer with the ID of a running one replaces it, so a model can
+// re-issue the command, for example to change the interval, without
+// stacking duplicate timers.
+func EveryWithID(id string, d time.Duration, fn func(time.Time) Msg) Cmd {
 	return func() Msg {
 		return everyMsg{
+			id:       id,
 			duration: d,
 			fn:       fn,
 		}
@@ -51,10 +113,74 @@ func Every(d time.Duration, fn func(time.Time) Msg) Cmd {
 
 // everyMsg is the internal message type for recurring timer commands.
 type everyMsg struct {
+	id       string
 	duration time.Duration
 	fn       func(time.Time) Msg
 }
 
+// StopTimer creates a command that stops the timer started with EveryWithID
+// and the given ID. Stopping an ID that is not running does nothing.
+func StopTimer(id string) Cmd {
+	return func() Msg {
+		return stopTimerMsg{id: id}
+	}
+}
+
+// stopTimerMsg is the internal message type for stopping timers.
+type stopTimerMsg struct {
+	id string
+}
+
+// CmdCtx creates a command that receives a context. The context is cancelled
+// when the program quits, so a command blocked on I/O can return early.
+func CmdCtx(fn func(ctx context.Context) Msg) Cmd {
+	return CmdCtxWithID("", fn)
+}
+
+// CmdCtxWithID is like CmdCtx, and the command can also be aborted with
+// Cancel(id). The message of a cancelled command is dropped. Running a
+// command with the ID of one that is still running cancels the earlier one,
+// so a model can start a new request when the user navigates away.
+func CmdCtxWithID(id string, fn func(ctx context.Context) Msg) Cmd {
+	return func() Msg {
+		return ctxCmdMsg{id: id, fn: fn}
+	}
+}
+
+// ctxCmdMsg is the internal message type for context-taking commands.
+type ctxCmdMsg struct {
+	id string
+	fn func(ctx context.Context) Msg
+}
+
+// Cancel creates a command that cancels the context of the running command
+// started with CmdCtxWithID and the given ID. Cancelling an ID that is not
+// running does nothing.
+func Cancel(id string) Cmd {
+	return func() Msg {
+		return cancelMsg{id: id}
+	}
+}
+
+// cancelMsg is the internal message type for cancelling keyed commands.
+type cancelMsg struct {
+	id string
+}
+
+// LeakedCommandsError is returned by Run when commands were still running
+// after the shutdown timeout.
+type LeakedCommandsError struct {
+	// Commands names the commands that were still running, by their ID
+	// for commands started with CmdCtxWithID, or by their function name.
+	Commands []string
+}
+
+// Error returns a description of the leaked commands.
+func (e *LeakedCommandsError) Error() string {
+	return fmt.Sprintf("%d commands still running after shutdown: %s",
+		len(e.Commands), strings.Join(e.Commands, ", "))
+}
+
 // CommandExecutor manages asynchronous command execution.
 // It executes commands in separate goroutines and delivers their messages
 // to the program's message channel in a thread-safe manner.
@@ -63,18 +189,99 @@ type CommandExecutor struct {
 	ctx     context.Context
 	wg      sync.WaitGroup
 	mu      sync.Mutex
-	timers  map[*time.Ticker]context.CancelFunc
+	timers  map[Ticker]context.CancelFunc
+
+	// timerIDs maps the IDs of EveryWithID timers to their tickers
+	timerIDs map[string]Ticker
+
+	// cmdCtx is the parent of the contexts of CmdCtx commands. It is
+	// cancelled on shutdown.
+	cmdCtx     context.Context
+	cancelCmds context.CancelFunc
+
+	// keyed holds the cancel functions of running CmdCtxWithID commands
+	keyed map[string]*keyedCmd
+
+	// subscriptions holds the cancel functions of running subscriptions
+	subscriptions map[string]context.CancelFunc
+
+	// running names the commands and timers that have not finished yet,
+	// for reporting leaks on shutdown
+	running map[uint64]string
+	lastRun uint64
+
+	// queue applies the message policies of the program to delivered
+	// messages
+	queue *messageQueue
+
+	// slots limits the number of commands running at once; nil means no
+	// limit
+	slots chan struct{}
+
+	// queued holds the commands waiting for a worker slot, oldest first
+	queued []queuedCmd
+
+	// clock is the source of time for Tick, Every and subscription
+	// restarts
+	clock Clock
+}
+
+// workerSlot records whether the goroutine of a command holds a worker
+// slot, so it only ever gives back a slot it took.
+type workerSlot struct {
+	held bool
+}
+
+// queuedCmd is a command waiting for a worker slot.
+type queuedCmd struct {
+	run uint64
+	cmd Cmd
+}
+
+// keyedCmd is a running command started with CmdCtxWithID.
+type keyedCmd struct {
+	cancel context.CancelFunc
 }
 
 // NewCommandExecutor creates a new CommandExecutor that delivers messages to the given channel.
 func NewCommandExecutor(ctx context.Context, msgChan chan Msg) *CommandExecutor {
+	return newCommandExecutor(ctx, msgChan, newMessageQueue(nil), 0)
+}
+
+// newCommandExecutor creates a CommandExecutor that delivers messages to
+// msgChan with the policies of queue, and runs at most maxWorkers commands
+// at once. A maxWorkers of 0 means no limit.
+func newCommandExecutor(ctx context.Context, msgChan chan Msg, queue *messageQueue, maxWorkers int) *CommandExecutor {
+	var slots chan struct{}
+	if maxWorkers > 0 {
+		slots = make(chan struct{}, maxWorkers)
+	}
+
+	cmdCtx, cancelCmds := context.WithCancel(ctx)
 	return &CommandExecutor{
-		msgChan: msgChan,
-		ctx:     ctx,
-		timers:  make(map[*time.Ticker]context.CancelFunc),
+		msgChan:    msgChan,
+		ctx:        ctx,
+		timers:     make(map[Ticker]context.CancelFunc),
+		timerIDs:   make(map[string]Ticker),
+		cmdCtx:     cmdCtx,
+		cancelCmds: cancelCmds,
+		keyed:      make(map[string]*keyedCmd),
+		running:    make(map[uint64]string),
+
+		subscriptions: make(map[string]context.CancelFunc),
+
+		queue: queue,
+		slots: slots,
+		clock: RealClock(),
 	}
 }
 
+// SetClock sets the source of time for Tick, Every and subscription
+// restarts. It must be called before the first command is executed.
+func (ce *CommandExecutor) SetClock(clock Clock) {
+	ce.clock = clock
+}
+
 // Execute runs a command asynchronously and delivers its message to the message channel.
 // If the command is nil, this is a no-op.
 func (ce *CommandExecutor) Execute(cmd Cmd) {
@@ -82,36 +289,156 @@ func (ce *CommandExecutor) Execute(cmd Cmd) {
 		return
 	}
 
+	ce.wg.Add(1)
+	run := ce.track(funcName(cmd))
+	if ce.slots == nil {
+		go func() {
+			defer ce.wg.Done()
+			defer ce.untrack(run)
+			slot := workerSlot{held: true}
+			ce.run(run, cmd, &slot, false)
+		}()
+		return
+	}
+
+	// With a worker limit, the command waits in the queue instead of in a
+	// goroutine of its own, and starts a worker if a slot is free
+	ce.mu.Lock()
+	defer ce.mu.Unlock()
+	ce.queued = append(ce.queued, queuedCmd{run: run, cmd: cmd})
+	select {
+	case ce.slots <- struct{}{}:
+		ce.startWorker()
+	default:
+	}
+}
+
+// startWorker starts a goroutine that runs queued commands in a worker slot
+// taken by the caller, until the queue is empty. Must be called with ce.mu
+// held.
+func (ce *CommandExecutor) startWorker() {
 	ce.wg.Add(1)
 	go func() {
 		defer ce.wg.Done()
-		defer func() {
-			// Recover from panics in command execution
-			if r := recover(); r != nil {
-				Error("Command panicked: %v", r)
-				// Deliver error message to Update
-				ce.deliverMessage(ErrorMsg{Err: fmt.Errorf("command panic: %v", r)})
+		slot := workerSlot{held: true}
+		for slot.held {
+			ce.mu.Lock()
+			if len(ce.queued) == 0 {
+				slot.held = false
+				<-ce.slots
+				ce.mu.Unlock()
+				return
 			}
-		}()
+			next := ce.queued[0]
+			ce.queued[0] = queuedCmd{}
+			ce.queued = ce.queued[1:]
+			ce.mu.Unlock()
 
-		Debug("Executing command")
+			ce.runQueued(next, &slot)
+		}
+	}()
+}
 
-		// Execute the command and get the resulting message
-		msg := cmd()
+// runQueued runs a queued command in the calling worker, which holds slot.
+// Commands still queued when the context is cancelled are not run.
+func (ce *CommandExecutor) runQueued(q queuedCmd, slot *workerSlot) {
+	defer ce.wg.Done()
+	defer ce.untrack(q.run)
+	if ce.ctx.Err() != nil {
+		Debug("Context cancelled, command not run")
+		return
+	}
+	ce.run(q.run, q.cmd, slot, false)
+}
 
-		// Handle special message types
-		switch m := msg.(type) {
-		case batchMsg:
-			// Execute all batched commands
-			ce.ExecuteBatch(m.cmds)
-		case everyMsg:
-			// Start a recurring timer
-			ce.startTimer(m.duration, m.fn)
-		default:
-			// Deliver the message to the channel
-			ce.deliverMessage(msg)
+// acquire waits for a free worker slot unless slot already holds one. It
+// reports false if the context was cancelled first.
+func (ce *CommandExecutor) acquire(slot *workerSlot) bool {
+	if slot.held {
+		return true
+	}
+	if ce.slots != nil {
+		select {
+		case ce.slots <- struct{}{}:
+		case <-ce.ctx.Done():
+			return false
+		}
+	}
+	slot.held = true
+	return true
+}
+
+// release frees the worker slot held by slot, if any. A slot freed while
+// commands are queued goes to a new worker for them.
+func (ce *CommandExecutor) release(slot *workerSlot) {
+	if !slot.held {
+		return
+	}
+	slot.held = false
+	if ce.slots == nil {
+		return
+	}
+
+	ce.mu.Lock()
+	defer ce.mu.Unlock()
+	if len(ce.queued) > 0 {
+		ce.startWorker()
+		return
+	}
+	<-ce.slots
+}
+
+// run executes a command in the calling goroutine, which holds slot, and
+// handles its message. When sequenced is set, a batch returned by the
+// command is waited for, so the next command of a sequence starts after it.
+func (ce *CommandExecutor) run(run uint64, cmd Cmd, slot *workerSlot, sequenced bool) {
+	defer func() {
+		// Recover from panics in command execution
+		if r := recover(); r != nil {
+			Error("Command panicked: %v", r)
+			// Deliver error message to Update
+			ce.deliverMessage(ErrorMsg{Err: fmt.Errorf("command panic: %v", r)})
 		}
 	}()
+
+	Debug("Executing command")
+
+	// Execute the command and get the resulting message
+	msg := cmd()
+
+	// Handle special message types
+	switch m := msg.(type) {
+	case batchMsg:
+		// Execute all batched commands
+		if sequenced {
+			ce.runBatch(m.cmds, slot)
+		} else {
+			ce.ExecuteBatch(m.cmds)
+		}
+	case sequenceMsg:
+		// Execute the commands one after another
+		ce.runSequence(run, m.cmds, slot)
+	case tickMsg:
+		// Wait for a one-shot timer
+		ce.runTick(m, slot)
+	case everyMsg:
+		// Start a recurring timer
+		ce.startTimer(m.id, m.duration, m.fn)
+	case stopTimerMsg:
+		ce.stopTimer(m.id)
+	case ctxCmdMsg:
+		// Run the command with a cancellable context
+		ce.runWithContext(run, m)
+	case cancelMsg:
+		ce.cancelKeyed(m.id)
+	case subscribeMsg:
+		ce.subscribe(m)
+	case unsubscribeMsg:
+		ce.unsubscribe(m.key)
+	default:
+		// Deliver the message to the channel
+		ce.deliverMessage(msg)
+	}
 }
 
 // ExecuteBatch executes multiple commands concurrently and delivers all their messages.
@@ -123,8 +450,163 @@ func (ce *CommandExecutor) ExecuteBatch(cmds []Cmd) {
 	}
 }
 
+// runBatch executes commands concurrently and waits until all of them have
+// delivered their messages. The slot of the caller is given up while
+// waiting, and is not held on return if the context was cancelled.
+func (ce *CommandExecutor) runBatch(cmds []Cmd, slot *workerSlot) {
+	var batch sync.WaitGroup
+	for _, cmd := range cmds {
+		if cmd == nil {
+			continue
+		}
+		cmd := cmd
+		ce.wg.Add(1)
+		batch.Add(1)
+		run := ce.track(funcName(cmd))
+		go func() {
+			defer ce.wg.Done()
+			defer batch.Done()
+			defer ce.untrack(run)
+			var slot workerSlot
+			if !ce.acquire(&slot) {
+				return
+			}
+			defer ce.release(&slot)
+			ce.run(run, cmd, &slot, true)
+		}()
+	}
+
+	// Give up the slot of the sequence while waiting, so the batch can run
+	// with a worker limit of one
+	ce.release(slot)
+	batch.Wait()
+	if !ce.acquire(slot) {
+		Debug("Context cancelled while waiting for a batch")
+	}
+}
+
+// runSequence executes commands one after another in the calling goroutine,
+// so their messages are delivered in order. It stops early when the
+// executor's context is cancelled or the worker slot could not be taken
+// back.
+func (ce *CommandExecutor) runSequence(run uint64, cmds []Cmd, slot *workerSlot) {
+	for _, cmd := range cmds {
+		if ce.ctx.Err() != nil || !slot.held {
+			Debug("Context cancelled, stopping sequence")
+			return
+		}
+		if cmd != nil {
+			ce.run(run, cmd, slot, true)
+		}
+	}
+}
+
+// runTick waits for the duration of a Tick command on the clock and
+// delivers its message. Waiting does not take up a worker slot, and ends
+// early on shutdown.
+func (ce *CommandExecutor) runTick(m tickMsg, slot *workerSlot) {
+	ce.release(slot)
+	defer ce.acquire(slot)
+
+	timer := ce.clock.NewTimer(m.duration)
+	defer timer.Stop()
+
+	select {
+	case t := <-timer.C():
+		ce.deliverMessage(m.fn(t))
+	case <-ce.cmdCtx.Done():
+		Debug("Tick cancelled")
+	}
+}
+
+// runWithContext runs a context-taking command in the goroutine of the
+// command that returned it, and delivers its message unless its context was
+// cancelled.
+func (ce *CommandExecutor) runWithContext(run uint64, m ctxCmdMsg) {
+	ctx, cancel := context.WithCancel(ce.cmdCtx)
+	defer cancel()
+
+	if m.id != "" {
+		ce.rename(run, m.id)
+		k := &keyedCmd{cancel: cancel}
+		ce.mu.Lock()
+		if prev := ce.keyed[m.id]; prev != nil {
+			Debug("Replacing running command %q", m.id)
+			prev.cancel()
+		}
+		ce.keyed[m.id] = k
+		ce.mu.Unlock()
+
+		defer func() {
+			ce.mu.Lock()
+			if ce.keyed[m.id] == k {
+				delete(ce.keyed, m.id)
+			}
+			ce.mu.Unlock()
+		}()
+	} else {
+		ce.rename(run, funcName(m.fn))
+	}
+
+	msg := m.fn(ctx)
+	if ctx.Err() != nil {
+		Debug("Command %q was cancelled, dropping %T", m.id, msg)
+		return
+	}
+	ce.deliverMessage(msg)
+}
+
+// cancelKeyed cancels the context of the running command with the given ID.
+func (ce *CommandExecutor) cancelKeyed(id string) {
+	ce.mu.Lock()
+	defer ce.mu.Unlock()
+
+	if k := ce.keyed[id]; k != nil {
+		Debug("Cancelling command %q", id)
+		k.cancel()
+		delete(ce.keyed, id)
+	}
+}
+
+// track records a running command under name and returns its key.
+func (ce *CommandExecutor) track(name string) uint64 {
+	ce.mu.Lock()
+	defer ce.mu.Unlock()
+
+	ce.lastRun++
+	ce.running[ce.lastRun] = name
+	return ce.lastRun
+}
+
+// rename changes the name a running command is reported under.
+func (ce *CommandExecutor) rename(run uint64, name string) {
+	ce.mu.Lock()
+	defer ce.mu.Unlock()
+
+	if _, ok := ce.running[run]; ok {
+		ce.running[run] = name
+	}
+}
+
+// untrack removes a finished command.
+func (ce *CommandExecutor) untrack(run uint64) {
+	ce.mu.Lock()
+	defer ce.mu.Unlock()
+
+	delete(ce.running, run)
+}
+
+// funcName returns the name of a function for log and error messages.
+func funcName(fn interface{}) string {
+	if f := runtime.FuncForPC(reflect.ValueOf(fn).Pointer()); f != nil {
+		return f.Name()
+	}
+	return "unknown"
+}
+
 // deliverMessage sends a message to the message channel in a thread-safe manner.
-// It respects the context cancellation to avoid blocking on a closed channel.
+// It respects the context cancellation to avoid blocking on a closed channel,
+// and the message policies of the program.
 func (ce *CommandExecutor) deliverMessage(msg Msg) {
 	if msg == nil {
 		Debug("Skipping nil message delivery")
@@ -133,40 +615,61 @@ func (ce *CommandExecutor) deliverMessage(msg Msg) {
 
 	Debug("Delivering message: %T", msg)
 
-	select {
-	case ce.msgChan <- msg:
+	if ce.queue.send(ce.ctx, ce.msgChan, msg) {
 		Debug("Message delivered successfully")
-	case <-ce.ctx.Done():
-		Debug("Context cancelled, message not delivered")
+	} else {
+		Debug("Message not delivered")
 	}
 }
 
 // startTimer creates a recurring timer that sends messages at regular intervals.
-func (ce *CommandExecutor) startTimer(d time.Duration, fn func(time.Time) Msg) {
-	Debug("Starting timer with duration: %v", d)
-	ticker := time.NewTicker(d)
+// A timer with a non-empty id replaces the running timer with the same id.
+func (ce *CommandExecutor) startTimer(id string, d time.Duration, fn func(time.Time) Msg) {
+	Debug("Starting timer %q with duration: %v", id, d)
+	ticker := ce.clock.NewTicker(d)
 	timerCtx, cancel := context.WithCancel(ce.ctx)
 
 	ce.mu.Lock()
+	if id != "" {
+		if prev, ok := ce.timerIDs[id]; ok {
+			Debug("Replacing timer %q", id)
+			ce.timers[prev]()
+		}
+		ce.timerIDs[id] = ticker
+	}
 	ce.timers[ticker] = cancel
 	ce.mu.Unlock()
 
+	name := id
+	if name == "" {
+		name = fmt.Sprintf("Every(%v)", d)
+	}
+
 	ce.wg.Add(1)
+	run := ce.track(name)
 	go func() {
 		defer ce.wg.Done()
+		defer ce.untrack(run)
 		defer ticker.Stop()
 		defer func() {
 			ce.mu.Lock()
 			delete(ce.timers, ticker)
+			if id != "" && ce.timerIDs[id] == ticker {
+				delete(ce.timerIDs, id)
+			}
 			ce.mu.Unlock()
 			Debug("Timer stopped")
 		}()
 
 		for {
 			select {
-			case t := <-ticker.C:
+			case t := <-ticker.C():
 				Debug("Timer tick at %v", t)
 				msg := fn(t)
+				if timerCtx.Err() != nil {
+					// Stopped or replaced while fn was running
+					return
+				}
 				ce.deliverMessage(msg)
 			case <-timerCtx.Done():
 				Debug("Timer context cancelled")
@@ -179,23 +682,77 @@ func (ce *CommandExecutor) startTimer(d time.Duration, fn func(time.Time) Msg) {
 	}()
 }
 
+// stopTimer stops the timer with the given ID.
+func (ce *CommandExecutor) stopTimer(id string) {
+	ce.mu.Lock()
+	defer ce.mu.Unlock()
+
+	if ticker, ok := ce.timerIDs[id]; ok {
+		Debug("Stopping timer %q", id)
+		ce.timers[ticker]()
+		delete(ce.timerIDs, id)
+	}
+}
+
 // Shutdown stops all running commands and waits for them to complete.
-// It cancels all recurring timers and waits for all goroutines to finish.
+// It cancels all recurring timers, subscriptions and the contexts of CmdCtx
+// commands, and waits for all goroutines to finish.
 func (ce *CommandExecutor) Shutdown() {
+	ce.ShutdownTimeout(0)
+}
+
+// ShutdownTimeout is like Shutdown, but waits at most timeout for the
+// commands to finish. A timeout of 0 waits until they finish. If commands
+// are still running when it expires, it returns a *LeakedCommandsError
+// naming them; they are left running.
+func (ce *CommandExecutor) ShutdownTimeout(timeout time.Duration) error {
 	Debug("Shutting down command executor")
-	
-	// Cancel all timers
+
+	// Cancel all timers, subscriptions and context-taking commands
 	ce.mu.Lock()
 	timerCount := len(ce.timers)
 	for _, cancel := range ce.timers {
 		cancel()
 	}
 	ce.mu.Unlock()
+	ce.cancelCmds()
 
 	Debug("Cancelled %d timers", timerCount)
 
 	// Wait for all goroutines to finish
-	ce.wg.Wait()
-	
-	Debug("Command executor shutdown complete")
+	finished := make(chan struct{})
+	go func() {
+		ce.wg.Wait()
+		close(finished)
+	}()
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	select {
+	case <-finished:
+		Debug("Command executor shutdown complete")
+		return nil
+	case <-expired:
+	}
+
+	ce.mu.Lock()
+	leaked := make([]string, 0, len(ce.running))
+	for _, name := range ce.running {
+		leaked = append(leaked, name)
+	}
+	ce.mu.Unlock()
+	if len(leaked) == 0 {
+		// The last command finished while the timer fired
+		return nil
+	}
+	sort.Strings(leaked)
+
+	err := &LeakedCommandsError{Commands: leaked}
+	Warn("Command executor shutdown timed out: %v", err)
+	return err
 }
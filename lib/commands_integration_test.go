@@ -129,8 +129,8 @@ func TestCommandExecutor_QuitFlow(t *testing.T) {
 	// Wait for the message
 	select {
 	case msg := <-msgChan:
-		if _, ok := msg.(quitMsg); !ok {
-			t.Errorf("Expected quitMsg, got %T", msg)
+		if _, ok := msg.(QuitMsg); !ok {
+			t.Errorf("Expected QuitMsg, got %T", msg)
 		}
 	case <-time.After(1 * time.Second):
 		t.Fatal("Timeout waiting for quit message")
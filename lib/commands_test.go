@@ -2,6 +2,7 @@ package lib
 
 import (
 	"context"
+	"runtime"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -238,38 +239,39 @@ func TestTick(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	clock := NewFakeClock(fakeStart)
 	msgChan := make(chan Msg, 10)
 	executor := NewCommandExecutor(ctx, msgChan)
+	executor.SetClock(clock)
+	defer executor.Shutdown()
 
-	// Create a tick command with a short duration
+	// Create a tick command
 	duration := 50 * time.Millisecond
 	tickMsg := "tick"
 	cmd := Tick(duration, func(tm time.Time) Msg {
 		return tickMsg
 	})
 
-	start := time.Now()
 	executor.Execute(cmd)
+	clock.BlockUntil(1)
 
-	// Wait for the message
+	// The message must not arrive before the duration has passed
+	clock.Advance(duration - time.Millisecond)
+	select {
+	case msg := <-msgChan:
+		t.Fatalf("Message arrived too early: %v", msg)
+	default:
+	}
+
+	clock.Advance(time.Millisecond)
 	select {
 	case msg := <-msgChan:
-		elapsed := time.Since(start)
 		if msg != tickMsg {
 			t.Errorf("Expected message %q, got %q", tickMsg, msg)
 		}
-		// Verify the delay was approximately correct
-		if elapsed < duration {
-			t.Errorf("Message arrived too early: %v < %v", elapsed, duration)
-		}
-		if elapsed > duration*2 {
-			t.Errorf("Message arrived too late: %v > %v", elapsed, duration*2)
-		}
 	case <-time.After(1 * time.Second):
 		t.Fatal("Timeout waiting for tick message")
 	}
-
-	executor.Shutdown()
 }
 
 // TestEvery tests recurring timer command execution.
@@ -277,10 +279,12 @@ func TestEvery(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	clock := NewFakeClock(fakeStart)
 	msgChan := make(chan Msg, 20)
 	executor := NewCommandExecutor(ctx, msgChan)
+	executor.SetClock(clock)
 
-	// Create an every command with a short interval
+	// Create an every command
 	interval := 20 * time.Millisecond
 	var counter int32
 	cmd := Every(interval, func(tm time.Time) Msg {
@@ -288,45 +292,32 @@ func TestEvery(t *testing.T) {
 	})
 
 	executor.Execute(cmd)
+	clock.BlockUntil(1)
 
-	// Wait for multiple messages
+	// Each interval sends one message
 	expectedCount := 5
-	timeout := time.After(interval*time.Duration(expectedCount+2) + 100*time.Millisecond)
-	receivedCount := 0
-
-	for receivedCount < expectedCount {
+	for i := 1; i <= expectedCount; i++ {
+		clock.Advance(interval)
 		select {
 		case msg := <-msgChan:
-			if _, ok := msg.(int32); ok {
-				receivedCount++
+			if msg != int32(i) {
+				t.Errorf("Expected message %d, got %v", i, msg)
 			}
-		case <-timeout:
-			t.Fatalf("Timeout waiting for recurring messages, got %d/%d", receivedCount, expectedCount)
+		case <-time.After(1 * time.Second):
+			t.Fatalf("Timeout waiting for recurring messages, got %d/%d", i-1, expectedCount)
 		}
 	}
 
 	// Shutdown should stop the timer
 	executor.Shutdown()
-
-	// Give a bit of time to ensure no more messages arrive
-	time.Sleep(interval * 2)
-
-	// Drain any remaining messages
-	remainingCount := 0
-	for {
-		select {
-		case <-msgChan:
-			remainingCount++
-		default:
-			goto done
-		}
+	if n := clock.Waiters(); n != 0 {
+		t.Errorf("Timer still running after shutdown, %d waiters", n)
 	}
-done:
-
-	// We should have received at least expectedCount messages
-	totalReceived := receivedCount + remainingCount
-	if totalReceived < expectedCount {
-		t.Errorf("Expected at least %d messages, got %d", expectedCount, totalReceived)
+	clock.Advance(interval * 2)
+	select {
+	case msg := <-msgChan:
+		t.Errorf("Message after shutdown: %v", msg)
+	default:
 	}
 }
 
@@ -379,8 +370,8 @@ drained:
 // TestQuit tests the Quit command.
 func TestQuit(t *testing.T) {
 	msg := Quit()
-	if _, ok := msg.(quitMsg); !ok {
-		t.Errorf("Expected quitMsg, got %T", msg)
+	if _, ok := msg.(QuitMsg); !ok {
+		t.Errorf("Expected QuitMsg, got %T", msg)
 	}
 }
 
@@ -417,3 +408,539 @@ func TestCustomCommand(t *testing.T) {
 
 	executor.Shutdown()
 }
+
+// TestCmdCtx tests that a context-taking command delivers its message.
+func TestCmdCtx(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	msgChan := make(chan Msg, 10)
+	executor := NewCommandExecutor(ctx, msgChan)
+
+	executor.Execute(CmdCtx(func(ctx context.Context) Msg {
+		if ctx.Err() != nil {
+			t.Errorf("Expected live context, got %v", ctx.Err())
+		}
+		return "done"
+	}))
+
+	select {
+	case msg := <-msgChan:
+		if msg != "done" {
+			t.Errorf("Expected message %q, got %v", "done", msg)
+		}
+	case <-time.After(1 * time.Second):
+		t.Fatal("Timeout waiting for message")
+	}
+
+	executor.Shutdown()
+}
+
+// TestCmdCtx_Shutdown tests that shutdown cancels the context of a blocked
+// command instead of waiting for it forever.
+func TestCmdCtx_Shutdown(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	msgChan := make(chan Msg, 10)
+	executor := NewCommandExecutor(ctx, msgChan)
+
+	started := make(chan struct{})
+	executor.Execute(CmdCtx(func(ctx context.Context) Msg {
+		close(started)
+		<-ctx.Done()
+		return "aborted"
+	}))
+	<-started
+
+	if err := executor.ShutdownTimeout(time.Second); err != nil {
+		t.Errorf("Expected clean shutdown, got %v", err)
+	}
+
+	select {
+	case msg := <-msgChan:
+		t.Errorf("Expected cancelled message to be dropped, got %v", msg)
+	default:
+	}
+}
+
+// TestCancel tests that Cancel aborts a keyed command and drops its message.
+func TestCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	msgChan := make(chan Msg, 10)
+	executor := NewCommandExecutor(ctx, msgChan)
+
+	started := make(chan struct{})
+	aborted := make(chan struct{})
+	executor.Execute(CmdCtxWithID("fetch", func(ctx context.Context) Msg {
+		close(started)
+		<-ctx.Done()
+		close(aborted)
+		return "stale"
+	}))
+	<-started
+
+	executor.Execute(Cancel("fetch"))
+
+	select {
+	case <-aborted:
+	case <-time.After(1 * time.Second):
+		t.Fatal("Timeout waiting for the command to be cancelled")
+	}
+
+	executor.Shutdown()
+
+	select {
+	case msg := <-msgChan:
+		t.Errorf("Expected cancelled message to be dropped, got %v", msg)
+	default:
+	}
+}
+
+// TestCmdCtxWithID_Replace tests that starting a command with the ID of a
+// running one cancels the earlier command.
+func TestCmdCtxWithID_Replace(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	msgChan := make(chan Msg, 10)
+	executor := NewCommandExecutor(ctx, msgChan)
+
+	started := make(chan struct{})
+	executor.Execute(CmdCtxWithID("search", func(ctx context.Context) Msg {
+		close(started)
+		<-ctx.Done()
+		return "old"
+	}))
+	<-started
+
+	executor.Execute(CmdCtxWithID("search", func(ctx context.Context) Msg {
+		return "new"
+	}))
+
+	select {
+	case msg := <-msgChan:
+		if msg != "new" {
+			t.Errorf("Expected message %q, got %v", "new", msg)
+		}
+	case <-time.After(1 * time.Second):
+		t.Fatal("Timeout waiting for message")
+	}
+
+	executor.Shutdown()
+
+	select {
+	case msg := <-msgChan:
+		t.Errorf("Expected replaced message to be dropped, got %v", msg)
+	default:
+	}
+}
+
+// TestShutdownTimeout_Leaked tests that shutdown gives up on a command that
+// ignores cancellation and reports it.
+func TestShutdownTimeout_Leaked(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	msgChan := make(chan Msg, 10)
+	executor := NewCommandExecutor(ctx, msgChan)
+
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{})
+	executor.Execute(CmdCtxWithID("upload", func(ctx context.Context) Msg {
+		close(started)
+		<-release
+		return nil
+	}))
+	<-started
+
+	err := executor.ShutdownTimeout(20 * time.Millisecond)
+	leaked, ok := err.(*LeakedCommandsError)
+	if !ok {
+		t.Fatalf("Expected *LeakedCommandsError, got %v", err)
+	}
+	if len(leaked.Commands) != 1 || leaked.Commands[0] != "upload" {
+		t.Errorf("Expected leaked command %q, got %v", "upload", leaked.Commands)
+	}
+}
+
+// TestSequence tests that sequenced commands run one after another and
+// deliver their messages in order.
+func TestSequence(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	msgChan := make(chan Msg, 10)
+	executor := NewCommandExecutor(ctx, msgChan)
+
+	// The first command is the slowest, so a concurrent run would reorder
+	// the messages
+	cmd1 := func() Msg {
+		time.Sleep(30 * time.Millisecond)
+		return "msg1"
+	}
+	cmd2 := func() Msg {
+		time.Sleep(10 * time.Millisecond)
+		return "msg2"
+	}
+	cmd3 := func() Msg { return "msg3" }
+
+	executor.Execute(Sequence(cmd1, nil, cmd2, cmd3))
+	executor.Shutdown()
+
+	expected := []string{"msg1", "msg2", "msg3"}
+	for _, exp := range expected {
+		select {
+		case msg := <-msgChan:
+			if msg != exp {
+				t.Errorf("Expected message %q, got %v", exp, msg)
+			}
+		default:
+			t.Fatalf("Expected message %q not received", exp)
+		}
+	}
+}
+
+// TestSequence_Nested tests that a Batch inside a Sequence completes before
+// the next command starts, and that sequences nest.
+func TestSequence_Nested(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	msgChan := make(chan Msg, 10)
+	executor := NewCommandExecutor(ctx, msgChan)
+
+	slow := func() Msg {
+		time.Sleep(30 * time.Millisecond)
+		return "batch"
+	}
+	fast := func() Msg { return "batch" }
+	first := func() Msg { return "first" }
+	last := func() Msg { return "last" }
+	inner := Sequence(func() Msg { return "inner1" }, func() Msg { return "inner2" })
+
+	executor.Execute(Sequence(first, Batch(slow, fast), inner, last))
+	executor.Shutdown()
+
+	expected := []string{"first", "batch", "batch", "inner1", "inner2", "last"}
+	for _, exp := range expected {
+		select {
+		case msg := <-msgChan:
+			if msg != exp {
+				t.Errorf("Expected message %q, got %v", exp, msg)
+			}
+		default:
+			t.Fatalf("Expected message %q not received", exp)
+		}
+	}
+}
+
+// TestSequence_Panic tests that a panicking command reports an error and the
+// sequence continues.
+func TestSequence_Panic(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	msgChan := make(chan Msg, 10)
+	executor := NewCommandExecutor(ctx, msgChan)
+
+	executor.Execute(Sequence(
+		func() Msg { panic("boom") },
+		func() Msg { return "after" },
+	))
+	executor.Shutdown()
+
+	if msg := <-msgChan; msg == nil {
+		t.Fatal("Expected error message")
+	} else if _, ok := msg.(ErrorMsg); !ok {
+		t.Errorf("Expected ErrorMsg, got %T", msg)
+	}
+	if msg := <-msgChan; msg != "after" {
+		t.Errorf("Expected message %q, got %v", "after", msg)
+	}
+}
+
+// TestEveryWithID_Stop tests that StopTimer stops a timer by its ID.
+func TestEveryWithID_Stop(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	msgChan := make(chan Msg, 20)
+	executor := NewCommandExecutor(ctx, msgChan)
+	defer executor.Shutdown()
+
+	interval := 10 * time.Millisecond
+	executor.Execute(EveryWithID("poll", interval, func(tm time.Time) Msg {
+		return "tick"
+	}))
+
+	select {
+	case <-msgChan:
+	case <-time.After(1 * time.Second):
+		t.Fatal("Timeout waiting for the first tick")
+	}
+
+	executor.Execute(StopTimer("poll"))
+
+	// Wait for the timer to stop, then drain ticks sent before it did
+	deadline := time.After(1 * time.Second)
+	for {
+		executor.mu.Lock()
+		running := len(executor.timers)
+		executor.mu.Unlock()
+		if running == 0 {
+			break
+		}
+		select {
+		case <-deadline:
+			t.Fatal("Timeout waiting for the timer to stop")
+		case <-time.After(interval):
+		}
+	}
+	for len(msgChan) > 0 {
+		<-msgChan
+	}
+
+	time.Sleep(interval * 3)
+	select {
+	case msg := <-msgChan:
+		t.Errorf("Expected no messages after StopTimer, got %v", msg)
+	default:
+	}
+}
+
+// TestEveryWithID_Replace tests that re-registering an ID replaces the
+// running timer instead of adding another one.
+func TestEveryWithID_Replace(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	msgChan := make(chan Msg, 100)
+	executor := NewCommandExecutor(ctx, msgChan)
+	defer executor.Shutdown()
+
+	interval := 10 * time.Millisecond
+	var old atomic.Int32
+	executor.Execute(EveryWithID("poll", interval, func(tm time.Time) Msg {
+		old.Add(1)
+		return "old"
+	}))
+
+	// Wait for the first timer to tick before replacing it
+	select {
+	case <-msgChan:
+	case <-time.After(1 * time.Second):
+		t.Fatal("Timeout waiting for the first tick")
+	}
+
+	executor.Execute(EveryWithID("poll", interval, func(tm time.Time) Msg {
+		return "new"
+	}))
+
+	// Once the new timer ticks, the old one must not tick any more
+	deadline := time.After(1 * time.Second)
+	for {
+		select {
+		case msg := <-msgChan:
+			if msg != "new" {
+				continue
+			}
+		case <-deadline:
+			t.Fatal("Timeout waiting for the replacing timer")
+		}
+		break
+	}
+	ticks := old.Load()
+	time.Sleep(interval * 3)
+	if got := old.Load(); got != ticks {
+		t.Errorf("Expected replaced timer to stop, it ticked %d more times", got-ticks)
+	}
+
+	executor.mu.Lock()
+	running := len(executor.timers)
+	executor.mu.Unlock()
+	if running != 1 {
+		t.Errorf("Expected 1 running timer, got %d", running)
+	}
+}
+
+// TestCommandExecutor_MaxWorkers tests that no more commands than the
+// worker limit run at once.
+func TestCommandExecutor_MaxWorkers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	msgChan := make(chan Msg, 20)
+	executor := newCommandExecutor(ctx, msgChan, newMessageQueue(nil), 2)
+
+	var running, peak int32
+	cmd := func() Msg {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		return "done"
+	}
+
+	for i := 0; i < 8; i++ {
+		executor.Execute(cmd)
+	}
+	executor.Shutdown()
+
+	if len(msgChan) != 8 {
+		t.Errorf("Expected 8 messages, got %d", len(msgChan))
+	}
+	if peak != 2 {
+		t.Errorf("Expected at most 2 commands at once, peak was %d", peak)
+	}
+}
+
+// TestCommandExecutor_QueuedCommands tests that commands waiting for a
+// worker slot are queued in order instead of each waiting in a goroutine.
+func TestCommandExecutor_QueuedCommands(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	msgChan := make(chan Msg, 1001)
+	executor := newCommandExecutor(ctx, msgChan, newMessageQueue(nil), 1)
+
+	unblock := make(chan struct{})
+	executor.Execute(func() Msg {
+		<-unblock
+		return -1
+	})
+
+	before := runtime.NumGoroutine()
+	for i := 0; i < 1000; i++ {
+		i := i
+		executor.Execute(func() Msg { return i })
+	}
+	if n := runtime.NumGoroutine() - before; n > 0 {
+		t.Errorf("Queued commands started %d goroutines", n)
+	}
+
+	close(unblock)
+	executor.Shutdown()
+	if msg := <-msgChan; msg != -1 {
+		t.Fatalf("Expected the blocking command first, got %v", msg)
+	}
+	for i := 0; i < 1000; i++ {
+		if msg := <-msgChan; msg != i {
+			t.Fatalf("Expected message %d, got %v", i, msg)
+		}
+	}
+}
+
+// TestCommandExecutor_QueuedAfterCancel tests that queued commands are not
+// run once the context is cancelled, and shutdown does not wait for them.
+func TestCommandExecutor_QueuedAfterCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	msgChan := make(chan Msg, 10)
+	executor := newCommandExecutor(ctx, msgChan, newMessageQueue(nil), 1)
+
+	unblock := make(chan struct{})
+	executor.Execute(func() Msg {
+		<-unblock
+		return nil
+	})
+	executor.Execute(func() Msg { return "late" })
+
+	cancel()
+	close(unblock)
+	if err := executor.ShutdownTimeout(time.Second); err != nil {
+		t.Fatalf("ShutdownTimeout() error = %v", err)
+	}
+	if len(msgChan) != 0 {
+		t.Errorf("Queued command ran after cancellation: %v", <-msgChan)
+	}
+}
+
+// TestSequence_MaxWorkers tests that a Batch inside a Sequence completes
+// with a worker limit of one.
+func TestSequence_MaxWorkers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	msgChan := make(chan Msg, 10)
+	executor := newCommandExecutor(ctx, msgChan, newMessageQueue(nil), 1)
+
+	msg := func(s string) Cmd {
+		return func() Msg { return s }
+	}
+	executor.Execute(Sequence(msg("a"), Batch(msg("b"), msg("b")), msg("c")))
+
+	if err := executor.ShutdownTimeout(time.Second); err != nil {
+		t.Fatalf("Sequence did not complete: %v", err)
+	}
+	if len(msgChan) != 4 {
+		t.Errorf("Expected 4 messages, got %d", len(msgChan))
+	}
+}
+
+// TestCommandExecutor_SlotOwnership tests that a goroutine only gives back a
+// worker slot it holds.
+func TestCommandExecutor_SlotOwnership(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	executor := newCommandExecutor(ctx, make(chan Msg, 1), newMessageQueue(nil), 1)
+
+	var owner, other workerSlot
+	if !executor.acquire(&owner) {
+		t.Fatal("Expected a free slot")
+	}
+	executor.release(&other)
+	if len(executor.slots) != 1 {
+		t.Error("Release without acquire freed the slot of another goroutine")
+	}
+
+	cancel()
+	if executor.acquire(&other) {
+		t.Error("Expected acquire to fail on a full executor after cancellation")
+	}
+	executor.release(&other)
+	if len(executor.slots) != 1 {
+		t.Error("Release after a failed acquire freed the slot of another goroutine")
+	}
+
+	executor.release(&owner)
+	executor.release(&owner)
+	if len(executor.slots) != 0 {
+		t.Errorf("Expected the slot to be free, %d taken", len(executor.slots))
+	}
+}
+
+// TestRunBatch_CancelledReacquire tests that a sequence whose batch finishes
+// after cancellation, while another goroutine took the slot, does not give
+// back that goroutine's slot.
+func TestRunBatch_CancelledReacquire(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	executor := newCommandExecutor(ctx, make(chan Msg, 10), newMessageQueue(nil), 1)
+
+	var slot workerSlot
+	executor.acquire(&slot)
+	executor.runBatch([]Cmd{func() Msg {
+		// Another goroutine waits for the slot this command holds, and
+		// gets it as soon as the command finishes
+		go func() { executor.slots <- struct{}{} }()
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+		return nil
+	}}, &slot)
+	executor.release(&slot)
+
+	if slot.held {
+		t.Error("Expected the slot not to be held after a cancelled re-acquire")
+	}
+	if len(executor.slots) != 1 {
+		t.Errorf("Expected the other goroutine to keep its slot, %d taken", len(executor.slots))
+	}
+}
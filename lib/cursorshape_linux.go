@@ -0,0 +1,60 @@
+package lib
+
+import (
+	"github.com/neurlang/wayland/wl"
+)
+
+// Compositors with the cursor-shape-v1 protocol draw pointer shapes from
+// their own cursor theme, by name. The wl package has no bindings for it,
+// so these proxies implement the requests the program uses.
+
+// cursorShapeInterface is the interface name of the manager global.
+const cursorShapeInterface = "wp_cursor_shape_manager_v1"
+
+// cursorShapeManager is a wp_cursor_shape_manager_v1.
+type cursorShapeManager struct {
+	wl.BaseProxy
+}
+
+// newCursorShapeManager registers a manager to be bound.
+func newCursorShapeManager(ctx *wl.Context) *cursorShapeManager {
+	m := new(cursorShapeManager)
+	ctx.Register(m)
+	return m
+}
+
+// getPointer creates the cursor shape device of a pointer.
+func (m *cursorShapeManager) getPointer(pointer *wl.Pointer) (*cursorShapeDevice, error) {
+	dev := new(cursorShapeDevice)
+	m.Context().Register(dev)
+	return dev, m.Context().SendRequest(m, 1, dev, pointer)
+}
+
+// destroy destroys the manager.
+func (m *cursorShapeManager) destroy() {
+	_ = m.Context().SendRequest(m, 0)
+	m.Unregister()
+}
+
+// Dispatch implements wl.Dispatcher. The manager has no events.
+func (m *cursorShapeManager) Dispatch(ev *wl.Event) {}
+
+// cursorShapeDevice is a wp_cursor_shape_device_v1.
+type cursorShapeDevice struct {
+	wl.BaseProxy
+}
+
+// setShape shows a shape of the wp_cursor_shape_device_v1 shape enum on the
+// pointer. serial is the serial of the pointer entering the surface.
+func (d *cursorShapeDevice) setShape(serial, shape uint32) error {
+	return d.Context().SendRequest(d, 1, serial, shape)
+}
+
+// destroy destroys the device.
+func (d *cursorShapeDevice) destroy() {
+	_ = d.Context().SendRequest(d, 0)
+	d.Unregister()
+}
+
+// Dispatch implements wl.Dispatcher. The device has no events.
+func (d *cursorShapeDevice) Dispatch(ev *wl.Event) {}
@@ -0,0 +1,117 @@
+package lib
+
+import (
+	"net/url"
+	"strings"
+)
+
+// dropMIMETypes lists the formats accepted from drops, most preferred first.
+var dropMIMETypes = []string{
+	"text/uri-list",
+	"text/plain;charset=utf-8",
+	"UTF8_STRING",
+	"text/plain",
+}
+
+// preferredDropMIME returns the accepted format to request from the offered
+// ones, or "" if none is accepted.
+func preferredDropMIME(offered []string) string {
+	for _, want := range dropMIMETypes {
+		for _, mime := range offered {
+			if mime == want {
+				return mime
+			}
+		}
+	}
+	return ""
+}
+
+// parseURIList parses a text/uri-list: one URI per line, with lines
+// starting with '#' being comments.
+func parseURIList(data string) []string {
+	var uris []string
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		uris = append(uris, line)
+	}
+	return uris
+}
+
+// uriToPath returns the local file path of a file:// URI.
+func uriToPath(uri string) (string, bool) {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return "", false
+	}
+	if u.Host != "" && u.Host != "localhost" {
+		return "", false
+	}
+	return u.Path, u.Path != ""
+}
+
+// newDropMsg creates a DropMsg for data dropped in the given format at the
+// given cell.
+func newDropMsg(mime string, data []byte, x, y int) DropMsg {
+	msg := DropMsg{
+		MIME: mime,
+		Text: string(data),
+		X:    x,
+		Y:    y,
+	}
+
+	if mime == "text/uri-list" {
+		msg.URIs = parseURIList(msg.Text)
+		for _, uri := range msg.URIs {
+			if path, ok := uriToPath(uri); ok {
+				msg.Paths = append(msg.Paths, path)
+			}
+		}
+	}
+	return msg
+}
+
+// dragEnter sends DragEnterMsg when a drag offering the given formats
+// enters the window at the given pixel position.
+func (p *Program) dragEnter(mimeTypes []string, x, y float32) {
+	p.mu.Lock()
+	p.dragActive = true
+	cellX, cellY := p.cellAt(x, y)
+	p.mu.Unlock()
+
+	Debug("Drag entered at cell (%d, %d) offering %v", cellX, cellY, mimeTypes)
+	p.postEvent(DragEnterMsg{MIME: mimeTypes, X: cellX, Y: cellY})
+}
+
+// dragLeave sends DragLeaveMsg when a drag leaves the window without a drop.
+func (p *Program) dragLeave() {
+	p.mu.Lock()
+	wasActive := p.dragActive
+	p.dragActive = false
+	p.mu.Unlock()
+
+	if wasActive {
+		p.postEvent(DragLeaveMsg{})
+	}
+}
+
+// drop sends DropMsg for data received from a drop at the given pixel
+// position. It is called from the goroutine that read the data, so it
+// waits for room in the queue like Send.
+func (p *Program) drop(mime string, data []byte, x, y float32) {
+	p.mu.Lock()
+	p.dragActive = false
+	cellX, cellY := p.cellAt(x, y)
+	p.mu.Unlock()
+
+	Debug("Dropped %d bytes of %s at cell (%d, %d)", len(data), mime, cellX, cellY)
+	p.Send(newDropMsg(mime, data, cellX, cellY))
+}
+
+// dropFinishedMsg is an internal message that releases the offer of a drop
+// on the display thread once its data was read.
+type dropFinishedMsg struct {
+	finish func()
+}
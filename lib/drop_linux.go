@@ -0,0 +1,476 @@
+package lib
+
+import (
+	"io"
+	"os"
+	"sync"
+
+	"github.com/neurlang/wayland/window"
+	"github.com/neurlang/wayland/wl"
+	"github.com/neurlang/wayland/wlclient"
+	"github.com/neurlang/wayland/wlcursor"
+)
+
+// dropTargets maps each display to the data device receiving its drags, and
+// the windows on it to their programs. The window backend only reports
+// drags entering a window, so the programs bind a data device of their own
+// to also see them move, leave and drop. The device also sets the clipboard
+// and the primary selection.
+var dropTargets = struct {
+	sync.Mutex
+	devices  map[*window.Display]*dropDevice
+	programs map[*window.Window]*Program
+}{
+	devices:  make(map[*window.Display]*dropDevice),
+	programs: make(map[*window.Window]*Program),
+}
+
+// setupDragAndDrop registers the window for drag events, creating the data
+// device of its display on the first window.
+func (p *Program) setupDragAndDrop() {
+	dropTargets.Lock()
+	defer dropTargets.Unlock()
+
+	dropTargets.programs[p.window] = p
+	acquireDataDevice(p.display)
+}
+
+// teardownDragAndDrop unregisters the window from drag events and forgets a
+// drag over it. The data device is released with the last window, and when
+// the program that owns the display exits.
+func (p *Program) teardownDragAndDrop() {
+	dropTargets.Lock()
+	defer dropTargets.Unlock()
+
+	delete(dropTargets.programs, p.window)
+	dev := dropTargets.devices[p.display]
+	if dev == nil {
+		return
+	}
+	if dev.target == p {
+		dev.endDrag()
+	}
+	if p.opener == nil {
+		// The windows it opened are closed with it
+		dev.destroy()
+		delete(dropTargets.devices, p.display)
+		return
+	}
+	releaseDataDevice(p.display)
+}
+
+// attachClipboard binds the data device of the host window's display for an
+// embedded program, which copies selected text through it.
+func (p *Program) attachClipboard() {
+	if p.window.Display == nil {
+		Debug("Host window has no display, selected text will not be copied")
+		return
+	}
+	dropTargets.Lock()
+	defer dropTargets.Unlock()
+	p.hostDisplay = p.window.Display
+	acquireDataDevice(p.hostDisplay)
+}
+
+// detachClipboard releases the data device of an embedded program. It can
+// be called more than once.
+func (p *Program) detachClipboard() {
+	dropTargets.Lock()
+	defer dropTargets.Unlock()
+	if p.hostDisplay != nil {
+		releaseDataDevice(p.hostDisplay)
+		p.hostDisplay = nil
+	}
+}
+
+// acquireDataDevice adds a user to the data device of a display, binding it
+// for the first one. Must be called with dropTargets locked.
+func acquireDataDevice(display *window.Display) {
+	dev := dropTargets.devices[display]
+	if dev == nil {
+		if dev = newDropDevice(display); dev == nil {
+			return
+		}
+		dropTargets.devices[display] = dev
+	}
+	dev.users++
+}
+
+// releaseDataDevice removes a user from the data device of a display, and
+// releases the device after the last one. Must be called with dropTargets
+// locked.
+func releaseDataDevice(display *window.Display) {
+	dev := dropTargets.devices[display]
+	if dev == nil {
+		return
+	}
+	if dev.users--; dev.users > 0 {
+		return
+	}
+	dev.destroy()
+	delete(dropTargets.devices, display)
+}
+
+// dropProgram returns the program of the window showing a surface.
+func dropProgram(surface *wl.Surface) *Program {
+	if surface == nil {
+		return nil
+	}
+	win, ok := wl.GetUserData[window.Window](surface)
+	if !ok {
+		return nil
+	}
+
+	dropTargets.Lock()
+	defer dropTargets.Unlock()
+	return dropTargets.programs[win]
+}
+
+// dropDevice is a wl_data_device that delivers drags to the programs of
+// the windows they are over, together with the primary selection device of
+// the same seat. Its handlers run on the display thread.
+type dropDevice struct {
+	registry    *wl.Registry
+	seat        *wl.Seat
+	seatVersion uint32
+	manager     *wl.DataDeviceManager
+	version     uint32
+	device      *wl.DataDevice
+
+	primaryManager *primarySelectionManager
+	primaryDevice  *primarySelectionDevice
+
+	// The pointer shows the pointer shapes of the programs, through the
+	// cursor shape device or from the cursor theme on the cursor surface
+	compositor         *wl.Compositor
+	shm                *wl.Shm
+	cursorShapeManager *cursorShapeManager
+	pointer            *wl.Pointer
+	cursorShape        *cursorShapeDevice
+	cursorSurface      *wl.Surface
+	cursorTheme        *wlcursor.Theme
+	cursors            map[PointerShape]*wlcursor.Cursor
+	themeFailed        bool
+
+	// pointerFocus is the window under the pointer, entered with
+	// pointerSerial. pointerShape is the shape shown on it if
+	// pointerShown, and pointerMoved is set once the pointer moved.
+	pointerFocus  *window.Window
+	pointerSerial uint32
+	pointerShape  PointerShape
+	pointerShown  bool
+	pointerMoved  bool
+
+	// users counts the windows and embedded programs using the device
+	users int
+
+	// clipboard and primary serve the text copied last
+	clipboard *clipboardSource
+	primary   *primarySource
+
+	// offers holds the offers announced by the compositor until an enter
+	// or selection event says what they are for
+	offers map[*wl.DataOffer]*dropOffer
+
+	// offer, target and the position describe the drag over a window
+	offer  *dropOffer
+	target *Program
+	x, y   float32
+}
+
+// dropOffer collects the formats of a data offer.
+type dropOffer struct {
+	offer *wl.DataOffer
+	types []string
+	mime  string
+}
+
+// newDropDevice binds a seat, the data device manager, the primary
+// selection manager and what the pointer needs on a registry of its own.
+// The devices are created once the compositor announced the seat and their
+// managers.
+func newDropDevice(display *window.Display) *dropDevice {
+	registry, err := display.Display.GetRegistry()
+	if err != nil {
+		Warn("Failed to get the registry for drag and drop: %v", err)
+		return nil
+	}
+
+	d := &dropDevice{registry: registry, offers: make(map[*wl.DataOffer]*dropOffer)}
+	registry.AddGlobalHandler(d)
+	return d
+}
+
+// HandleRegistryGlobal binds the first seat, the data device manager, the
+// primary selection manager, and the globals for the pointer.
+func (d *dropDevice) HandleRegistryGlobal(ev wl.RegistryGlobalEvent) {
+	switch ev.Interface {
+	case "wl_seat":
+		if d.seat != nil {
+			return
+		}
+		d.seatVersion = min(ev.Version, 5)
+		d.seat = wl.NewSeat(d.registry.Context())
+		if err := d.registry.Bind(ev.Name, ev.Interface, d.seatVersion, d.seat); err != nil {
+			Warn("Failed to bind the seat for drag and drop: %v", err)
+			return
+		}
+		d.seat.AddCapabilitiesHandler(d)
+	case "wl_data_device_manager":
+		d.version = min(ev.Version, 3)
+		d.manager = wl.NewDataDeviceManager(d.registry.Context())
+		if err := d.registry.Bind(ev.Name, ev.Interface, d.version, d.manager); err != nil {
+			Warn("Failed to bind the data device manager: %v", err)
+			return
+		}
+	case primarySelectionInterface:
+		d.primaryManager = newPrimarySelectionManager(d.registry.Context())
+		if err := d.registry.Bind(ev.Name, ev.Interface, 1, d.primaryManager); err != nil {
+			Warn("Failed to bind the primary selection manager: %v", err)
+			return
+		}
+	case cursorShapeInterface:
+		d.cursorShapeManager = newCursorShapeManager(d.registry.Context())
+		if err := d.registry.Bind(ev.Name, ev.Interface, 1, d.cursorShapeManager); err != nil {
+			Warn("Failed to bind the cursor shape manager: %v", err)
+			return
+		}
+		d.createCursorShapeDevice()
+		return
+	case "wl_compositor":
+		d.compositor = wl.NewCompositor(d.registry.Context())
+		if err := d.registry.Bind(ev.Name, ev.Interface, 1, d.compositor); err != nil {
+			Warn("Failed to bind the compositor for the pointer: %v", err)
+		}
+		return
+	case "wl_shm":
+		d.shm = wl.NewShm(d.registry.Context())
+		if err := d.registry.Bind(ev.Name, ev.Interface, 1, d.shm); err != nil {
+			Warn("Failed to bind shared memory for the pointer: %v", err)
+		}
+		return
+	default:
+		return
+	}
+
+	if d.seat == nil {
+		return
+	}
+	if d.device == nil && d.manager != nil {
+		device, err := d.manager.GetDataDevice(d.seat)
+		if err != nil {
+			Warn("Failed to create the data device for drag and drop: %v", err)
+			return
+		}
+		wlclient.DataDeviceAddListener(device, d)
+		d.device = device
+	}
+	if d.primaryDevice == nil && d.primaryManager != nil {
+		device, err := d.primaryManager.getDevice(d.seat)
+		if err != nil {
+			Warn("Failed to create the primary selection device: %v", err)
+			return
+		}
+		d.primaryDevice = device
+	}
+}
+
+// HandleDataDeviceDataOffer starts collecting the formats of a new offer.
+func (d *dropDevice) HandleDataDeviceDataOffer(ev wl.DataDeviceDataOfferEvent) {
+	if ev.Id == nil {
+		return
+	}
+	o := &dropOffer{offer: ev.Id}
+	wlclient.DataOfferAddListener(ev.Id, o)
+	d.offers[ev.Id] = o
+}
+
+// HandleDataDeviceEnter accepts the preferred format of a drag entering a
+// window and sends DragEnterMsg to its program.
+func (d *dropDevice) HandleDataDeviceEnter(ev wl.DataDeviceEnterEvent) {
+	d.endDrag()
+
+	o := d.offers[ev.Id]
+	delete(d.offers, ev.Id)
+	target := dropProgram(ev.Surface)
+	if o == nil || target == nil {
+		if o != nil {
+			o.destroy()
+		}
+		return
+	}
+
+	// Offers without an accepted format are rejected by not accepting any
+	o.mime = preferredDropMIME(o.types)
+	if o.mime != "" {
+		_ = o.offer.Accept(ev.Serial, o.mime)
+	}
+	if d.version >= 3 {
+		action := uint32(wl.DataDeviceManagerDndActionNone)
+		if o.mime != "" {
+			action = wl.DataDeviceManagerDndActionCopy
+		}
+		_ = o.offer.SetActions(action, action)
+	}
+
+	d.offer, d.target = o, target
+	d.x, d.y = ev.X, ev.Y
+	target.dragEnter(o.types, ev.X, ev.Y)
+}
+
+// HandleDataDeviceMotion remembers where the drag is, for the drop.
+func (d *dropDevice) HandleDataDeviceMotion(ev wl.DataDeviceMotionEvent) {
+	d.x, d.y = ev.X, ev.Y
+}
+
+// HandleDataDeviceLeave sends DragLeaveMsg when a drag leaves a window
+// without a drop.
+func (d *dropDevice) HandleDataDeviceLeave(ev wl.DataDeviceLeaveEvent) {
+	if d.target != nil {
+		d.target.dragLeave()
+	}
+	d.endDrag()
+}
+
+// HandleDataDeviceDrop receives the dropped data into a pipe. It is read on
+// another goroutine, so a slow source does not block the display thread,
+// and delivered as DropMsg. The offer is finished back on the display
+// thread once the data is read.
+func (d *dropDevice) HandleDataDeviceDrop(ev wl.DataDeviceDropEvent) {
+	o, target := d.offer, d.target
+	x, y := d.x, d.y
+	d.offer, d.target = nil, nil
+	if o == nil || target == nil {
+		return
+	}
+	if o.mime == "" {
+		target.dragLeave()
+		o.destroy()
+		return
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		Warn("Failed to receive dropped data: %v", err)
+		target.dragLeave()
+		o.destroy()
+		return
+	}
+	_ = o.offer.Receive(o.mime, w.Fd())
+	w.Close()
+
+	finish := func() {
+		if d.version >= 3 {
+			_ = o.offer.Finish()
+		}
+		o.destroy()
+	}
+	go func() {
+		data, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			Warn("Failed to read dropped data: %v", err)
+		}
+		target.drop(o.mime, data, x, y)
+		target.Send(dropFinishedMsg{finish: finish})
+	}()
+}
+
+// HandleDataDeviceSelection discards clipboard offers, which the program
+// reads through the window backend.
+func (d *dropDevice) HandleDataDeviceSelection(ev wl.DataDeviceSelectionEvent) {
+	if o := d.offers[ev.Id]; o != nil {
+		delete(d.offers, ev.Id)
+		o.destroy()
+	}
+}
+
+// endDrag forgets the drag over a window and destroys its offer.
+func (d *dropDevice) endDrag() {
+	if d.offer != nil {
+		d.offer.destroy()
+	}
+	d.offer, d.target = nil, nil
+}
+
+// destroy releases the devices, the seat and the registry, and the offers
+// and sources still held.
+func (d *dropDevice) destroy() {
+	d.endDrag()
+	for _, o := range d.offers {
+		o.destroy()
+	}
+	d.offers = nil
+	if d.clipboard != nil {
+		d.clipboard.destroy()
+		d.clipboard = nil
+	}
+	if d.primary != nil {
+		d.primary.destroy()
+		d.primary = nil
+	}
+
+	if d.primaryDevice != nil {
+		d.primaryDevice.destroy()
+		d.primaryDevice = nil
+	}
+	if d.primaryManager != nil {
+		d.primaryManager.destroy()
+		d.primaryManager = nil
+	}
+	d.destroyPointer()
+	if d.cursorShapeManager != nil {
+		d.cursorShapeManager.destroy()
+		d.cursorShapeManager = nil
+	}
+	if d.shm != nil {
+		d.shm.Unregister()
+		d.shm = nil
+	}
+	if d.compositor != nil {
+		d.compositor.Unregister()
+		d.compositor = nil
+	}
+
+	if d.device != nil {
+		if d.version >= 2 {
+			_ = d.device.Release()
+		}
+		wlclient.DataDeviceDestroy(d.device)
+		d.device = nil
+	}
+	if d.manager != nil {
+		wlclient.DataDeviceManagerDestroy(d.manager)
+		d.manager = nil
+	}
+	if d.seat != nil {
+		if d.seatVersion >= 5 {
+			_ = d.seat.Release()
+		}
+		d.seat.RemoveCapabilitiesHandler(d)
+		wlclient.SeatDestroy(d.seat)
+		d.seat = nil
+	}
+	if d.registry != nil {
+		d.registry.RemoveGlobalHandler(d)
+		wlclient.RegistryDestroy(d.registry)
+		d.registry = nil
+	}
+}
+
+// HandleDataOfferOffer records a format of the offer.
+func (o *dropOffer) HandleDataOfferOffer(ev wl.DataOfferOfferEvent) {
+	o.types = append(o.types, ev.MimeType)
+}
+
+// HandleDataOfferSourceActions implements wlclient.DataOfferListener.
+func (o *dropOffer) HandleDataOfferSourceActions(ev wl.DataOfferSourceActionsEvent) {}
+
+// HandleDataOfferAction implements wlclient.DataOfferListener.
+func (o *dropOffer) HandleDataOfferAction(ev wl.DataOfferActionEvent) {}
+
+// destroy releases the offer.
+func (o *dropOffer) destroy() {
+	_ = o.offer.Destroy()
+	wlclient.DataOfferDestroy(o.offer)
+}
@@ -0,0 +1,68 @@
+package lib
+
+import (
+	"testing"
+
+	"github.com/neurlang/wayland/wl"
+)
+
+// TestDropDevice_SecondaryWindowClosed tests that a drag over a secondary
+// window ends when the window closes, so the leave and drop events that
+// follow reach no program, and that the main program releases the device.
+func TestDropDevice_SecondaryWindowClosed(t *testing.T) {
+	p := NewProgram(nil)
+	child := newTestChild(p, p, 10)
+	dev := &dropDevice{offers: make(map[*wl.DataOffer]*dropOffer), target: child, users: 2}
+
+	dropTargets.Lock()
+	dropTargets.devices[p.display] = dev
+	dropTargets.programs[child.window] = child
+	dropTargets.Unlock()
+	defer func() {
+		dropTargets.Lock()
+		delete(dropTargets.devices, p.display)
+		dropTargets.Unlock()
+	}()
+
+	p.handleProgramMsg(closeWindowMsg{window: child})
+	if dev.target != nil {
+		t.Fatal("drag over the closed window was not ended")
+	}
+	if dropProgramFor(child) {
+		t.Error("closed window still receives drags")
+	}
+	dropTargets.Lock()
+	kept := dropTargets.devices[p.display] == dev
+	dropTargets.Unlock()
+	if !kept {
+		t.Fatal("closing a secondary window released the data device")
+	}
+
+	dev.HandleDataDeviceLeave(wl.DataDeviceLeaveEvent{})
+	dev.HandleDataDeviceDrop(wl.DataDeviceDropEvent{})
+	if len(child.msgChan) != 0 {
+		t.Errorf("closed window received %d drag messages", len(child.msgChan))
+	}
+	if msg := <-p.msgChan; msg != (WindowClosedMsg{ID: 10}) {
+		t.Errorf("opener received %v, want WindowClosedMsg for window 10", msg)
+	}
+	if len(p.msgChan) != 0 {
+		t.Errorf("opener received %d more messages", len(p.msgChan))
+	}
+
+	p.teardownDragAndDrop()
+	dropTargets.Lock()
+	_, kept = dropTargets.devices[p.display]
+	dropTargets.Unlock()
+	if kept || dev.offers != nil {
+		t.Error("main program did not release the data device")
+	}
+}
+
+// dropProgramFor reports whether the window of p is registered for drags.
+func dropProgramFor(p *Program) bool {
+	dropTargets.Lock()
+	defer dropTargets.Unlock()
+	_, ok := dropTargets.programs[p.window]
+	return ok
+}
@@ -0,0 +1,123 @@
+package lib
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPreferredDropMIME(t *testing.T) {
+	tests := []struct {
+		offered []string
+		want    string
+	}{
+		{[]string{"text/plain", "text/uri-list"}, "text/uri-list"},
+		{[]string{"UTF8_STRING", "text/plain;charset=utf-8"}, "text/plain;charset=utf-8"},
+		{[]string{"image/png"}, ""},
+		{nil, ""},
+	}
+
+	for _, tt := range tests {
+		if got := preferredDropMIME(tt.offered); got != tt.want {
+			t.Errorf("preferredDropMIME(%v) = %q, want %q", tt.offered, got, tt.want)
+		}
+	}
+}
+
+func TestNewDropMsg_URIList(t *testing.T) {
+	data := "# dropped from a file manager\r\n" +
+		"file:///home/user/My%20Notes.txt\r\n" +
+		"file://localhost/tmp/a.go\r\n" +
+		"https://example.com/page\r\n"
+
+	msg := newDropMsg("text/uri-list", []byte(data), 3, 4)
+
+	wantURIs := []string{
+		"file:///home/user/My%20Notes.txt",
+		"file://localhost/tmp/a.go",
+		"https://example.com/page",
+	}
+	if !reflect.DeepEqual(msg.URIs, wantURIs) {
+		t.Errorf("URIs = %v, want %v", msg.URIs, wantURIs)
+	}
+
+	wantPaths := []string{"/home/user/My Notes.txt", "/tmp/a.go"}
+	if !reflect.DeepEqual(msg.Paths, wantPaths) {
+		t.Errorf("Paths = %v, want %v", msg.Paths, wantPaths)
+	}
+	if msg.X != 3 || msg.Y != 4 {
+		t.Errorf("position = (%d, %d), want (3, 4)", msg.X, msg.Y)
+	}
+	if msg.Text != data {
+		t.Errorf("Text = %q, want the dropped data", msg.Text)
+	}
+}
+
+func TestNewDropMsg_Text(t *testing.T) {
+	msg := newDropMsg("text/plain", []byte("file:///not/a/path"), 0, 0)
+	if msg.Text != "file:///not/a/path" {
+		t.Errorf("Text = %q", msg.Text)
+	}
+	if msg.URIs != nil || msg.Paths != nil {
+		t.Errorf("plain text drop has URIs %v and paths %v", msg.URIs, msg.Paths)
+	}
+}
+
+func TestProgram_DragMessages(t *testing.T) {
+	p := NewProgram(nil)
+	p.msgChan = make(chan Msg, 8)
+	renderer, err := NewRenderer(RendererOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create renderer: %v", err)
+	}
+	p.renderer = renderer
+	cw, ch := float32(renderer.CellWidth()), float32(renderer.CellHeight())
+
+	p.dragEnter([]string{"text/uri-list"}, 2.5*cw, 1.5*ch)
+	p.dragLeave()
+	p.dragLeave()
+	p.dragEnter([]string{"text/plain"}, 0, 0)
+	p.drop("text/plain", []byte("hi"), 5.5*cw, 0)
+	p.dragLeave()
+
+	want := []Msg{
+		DragEnterMsg{MIME: []string{"text/uri-list"}, X: 2, Y: 1},
+		DragLeaveMsg{},
+		DragEnterMsg{MIME: []string{"text/plain"}, X: 0, Y: 0},
+		DropMsg{MIME: "text/plain", Text: "hi", X: 5, Y: 0},
+	}
+	for i, w := range want {
+		if got := <-p.msgChan; !reflect.DeepEqual(got, w) {
+			t.Errorf("message %d = %v, want %v", i, got, w)
+		}
+	}
+	if len(p.msgChan) != 0 {
+		t.Errorf("unexpected extra messages: %d", len(p.msgChan))
+	}
+}
+
+// TestProgram_DropFinished tests that a drop's offer is released on the
+// display thread and the internal message does not reach the model.
+func TestProgram_DropFinished(t *testing.T) {
+	p := NewProgram(logModel{})
+	renderer, err := NewRenderer(RendererOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create renderer: %v", err)
+	}
+	p.renderer = renderer
+	p.cmdExec = NewCommandExecutor(p.ctx, p.msgChan)
+	defer p.cmdExec.Shutdown()
+
+	finished := false
+	p.drop("text/plain", []byte("hi"), 0, 0)
+	p.Send(dropFinishedMsg{finish: func() { finished = true }})
+	p.Send(QuitMsg{})
+	p.Redraw(nil)
+
+	if !finished {
+		t.Error("offer of the drop was not finished")
+	}
+	want := []Msg{DropMsg{MIME: "text/plain", Text: "hi"}}
+	if got := p.model.(logModel).got; !reflect.DeepEqual(got, want) {
+		t.Errorf("model received %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,14 @@
+package lib
+
+// setupDragAndDrop registers for drag events on the window.
+// The Windows backend does not report drag and drop yet.
+func (p *Program) setupDragAndDrop() {}
+
+// teardownDragAndDrop unregisters the window from drag events.
+func (p *Program) teardownDragAndDrop() {}
+
+// attachClipboard does nothing on Windows, where copying needs no device.
+func (p *Program) attachClipboard() {}
+
+// detachClipboard does nothing on Windows.
+func (p *Program) detachClipboard() {}
@@ -0,0 +1,120 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/neurlang/wayland/window"
+)
+
+// ProgramStatus describes the state of an embedded Program for its host.
+type ProgramStatus struct {
+	// Bounds is the area of the host window the program draws into.
+	Bounds window.Rectangle
+
+	// Cols and Rows are the size of the grid in cells.
+	Cols int
+	Rows int
+
+	// Title is the window title last set by the model with SetWindowTitle.
+	Title string
+
+	// Focused reports whether the host forwarded keyboard focus to the
+	// program.
+	Focused bool
+
+	// NeedsRedraw reports whether the program has a frame or messages
+	// waiting, and the host should forward a redraw.
+	NeedsRedraw bool
+
+	// Done reports whether the model quit. The host should then remove
+	// the program with Detach.
+	Done bool
+}
+
+// NewEmbeddedProgram creates a Program that draws into the bounds rectangle
+// of a window owned by the caller, for hosts that use the window package
+// directly and show a model in one panel. The model's Init is called before
+// it returns.
+//
+// The host keeps the display loop and its window handlers, and forwards
+// events to the program by calling its window.WidgetHandler and
+// window.KeyboardHandler methods, such as Redraw, Motion, Button, Key and
+// Focus, with window coordinates. Resize is ignored; call SetBounds when the
+// panel moves or changes size. Commands that change the window, such as
+// SetWindowTitle or ToggleFullscreen, leave the host's window alone, and
+// Quit marks the program as done instead of exiting the display loop.
+func NewEmbeddedProgram(model Model, host *window.Window, bounds window.Rectangle, opts ...ProgramOption) (*Program, error) {
+	if host == nil {
+		return nil, errors.New("host window is nil")
+	}
+
+	p := NewProgram(model, opts...)
+	p.embedded = true
+	p.window = host
+
+	if err := p.validateOptions(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	if err := p.createRenderer(); err != nil {
+		return nil, err
+	}
+
+	p.attachClipboard()
+	p.SetBounds(bounds)
+	p.startModel()
+	return p, nil
+}
+
+// SetBounds moves an embedded program to another area of the host window
+// and sends WindowSizeMsg with the new size.
+func (p *Program) SetBounds(bounds window.Rectangle) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.bounds = bounds
+	p.layoutGrid(bounds)
+}
+
+// Status returns the state of an embedded program.
+func (p *Program) Status() ProgramStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return ProgramStatus{
+		Bounds:      p.bounds,
+		Cols:        p.windowWidth,
+		Rows:        p.windowHeight,
+		Title:       p.options.WindowTitle,
+		Focused:     p.focused,
+		NeedsRedraw: p.needsRedraw || p.queue.pending(p.msgChan) > 0 || p.motionPending,
+		Done:        p.ctx.Err() != nil,
+	}
+}
+
+// Detach stops an embedded program and waits up to the shutdown timeout for
+// its running commands to finish. It returns a *LeakedCommandsError if
+// commands were still running. The host should stop forwarding events to it
+// first.
+func (p *Program) Detach() error {
+	defer p.markDone()
+	p.detachClipboard()
+
+	p.cancel()
+	if p.cmdExec == nil {
+		return nil
+	}
+	return p.cmdExec.ShutdownTimeout(p.options.ShutdownTimeout)
+}
+
+// scheduleHostRedraw asks the host of an embedded program for a redraw,
+// through the widget it forwards redraws for. Must be called with p.mu held.
+func (p *Program) scheduleHostRedraw() {
+	if p.needsRedraw {
+		return
+	}
+	p.needsRedraw = true
+	if p.hostWidget != nil {
+		p.hostWidget.ScheduleRedraw()
+	}
+}
@@ -0,0 +1,80 @@
+package lib
+
+import (
+	"testing"
+
+	"github.com/neurlang/wayland/window"
+)
+
+// panelModel is a minimal model for embedding tests.
+type panelModel struct {
+	initCmd Cmd
+}
+
+func (m panelModel) Init() Cmd                   { return m.initCmd }
+func (m panelModel) Update(msg Msg) (Model, Cmd) { return m, nil }
+func (m panelModel) View() string                { return "panel" }
+
+func TestNewEmbeddedProgram(t *testing.T) {
+	if _, err := NewEmbeddedProgram(panelModel{}, nil, window.Rectangle{}); err == nil {
+		t.Error("NewEmbeddedProgram accepted a nil host window")
+	}
+	if _, err := NewEmbeddedProgram(panelModel{}, &window.Window{}, window.Rectangle{}, WithFontSize(0)); err == nil {
+		t.Error("NewEmbeddedProgram accepted invalid options")
+	}
+
+	bounds := window.Rectangle{X: 100, Y: 50, Width: 400, Height: 300}
+	p, err := NewEmbeddedProgram(panelModel{}, &window.Window{}, bounds)
+	if err != nil {
+		t.Fatalf("NewEmbeddedProgram() error = %v", err)
+	}
+	defer p.Detach()
+
+	cellWidth := p.renderer.CellWidth()
+	cellHeight := p.renderer.CellHeight()
+	msg := p.queue.drain(p.msgChan)[0].(WindowSizeMsg)
+	if msg.Width != int(bounds.Width/cellWidth) || msg.Height != int(bounds.Height/cellHeight) || msg.PixelWidth != 400 {
+		t.Errorf("size message = %v for a %dx%d panel", msg, bounds.Width, bounds.Height)
+	}
+
+	// Window coordinates are relative to the panel
+	if x, y := p.cellAt(float32(100+cellWidth*3), float32(50+cellHeight*2)); x != 3 || y != 2 {
+		t.Errorf("cellAt() = (%d, %d), want (3, 2)", x, y)
+	}
+
+	// Resize events for the host window are ignored
+	p.Resize(nil, 1000, 1000, 1000, 1000)
+	if status := p.Status(); status.Bounds != bounds || status.Cols != msg.Width {
+		t.Errorf("status after host resize = %+v", status)
+	}
+
+	p.SetBounds(window.Rectangle{Width: 200, Height: 100})
+	if status := p.Status(); status.Cols != int(200/cellWidth) || !status.NeedsRedraw {
+		t.Errorf("status after SetBounds = %+v", status)
+	}
+}
+
+func TestEmbeddedProgram_LeavesHostWindowAlone(t *testing.T) {
+	p, err := NewEmbeddedProgram(panelModel{}, &window.Window{}, window.Rectangle{Width: 80, Height: 40})
+	if err != nil {
+		t.Fatalf("NewEmbeddedProgram() error = %v", err)
+	}
+	defer p.Detach()
+
+	// These would call into the zero host window if they were applied
+	p.handleProgramMsg(SetWindowTitle("Panel")())
+	p.handleProgramMsg(ToggleFullscreen())
+	p.handleProgramMsg(Maximize())
+	p.handleProgramMsg(Restore())
+	p.handleProgramMsg(Minimize())
+
+	if status := p.Status(); status.Title != "Panel" {
+		t.Errorf("title = %q, want %q", status.Title, "Panel")
+	}
+
+	// Quitting marks the program done instead of exiting the display loop
+	p.quit()
+	if !p.Status().Done {
+		t.Error("embedded program not done after quitting")
+	}
+}
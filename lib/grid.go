@@ -45,6 +45,9 @@ type TerminalGrid struct {
 	Width  int
 	Height int
 	Cells  [][]Cell
+
+	// Zones lists the regions marked with Mark in the parsed view.
+	Zones []Zone
 }
 
 // NewTerminalGrid creates a new TerminalGrid with the specified dimensions.
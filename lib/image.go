@@ -0,0 +1,170 @@
+package lib
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+)
+
+// RenderImage renders a grid to an image with the colors of the theme and
+// the given font, without a window or Cairo surface. A nil font uses the
+// embedded font. The image is the size of the grid's cells, without
+// padding, and has the same pixels the window would show.
+func RenderImage(grid *TerminalGrid, theme Theme, font *Font) (*image.RGBA, error) {
+	r, err := NewRenderer(RendererOptions{
+		DefaultFg: theme.Foreground,
+		DefaultBg: theme.Background,
+		Font:      font,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.RenderImage(grid)
+}
+
+// RenderImage renders a grid to a new image with the renderer's font and
+// colors. The origin and bounds of the renderer are ignored.
+func (r *Renderer) RenderImage(grid *TerminalGrid) (*image.RGBA, error) {
+	if grid == nil {
+		return nil, fmt.Errorf("grid is nil")
+	}
+
+	cellWidth := r.font.CellWidth()
+	cellHeight := r.font.CellHeight()
+	img := image.NewRGBA(image.Rect(0, 0, grid.Width*cellWidth, grid.Height*cellHeight))
+
+	for y := 0; y < grid.Height; y++ {
+		for x := 0; x < grid.Width; x++ {
+			texture, bg, fg := r.cellTexture(x, y, grid.Cells[y][x])
+			if texture == nil {
+				fillImage(img, image.Rect(x*cellWidth, y*cellHeight, (x+1)*cellWidth, (y+1)*cellHeight), bg)
+				continue
+			}
+			for i := 0; i < cellHeight; i++ {
+				for j := 0; j < cellWidth && i*cellWidth+j < len(texture); j++ {
+					rgb := shade(texture[i*cellWidth+j], bg, fg)
+					img.SetRGBA(x*cellWidth+j, y*cellHeight+i, color.RGBA{rgb[0], rgb[1], rgb[2], 255})
+				}
+			}
+		}
+	}
+	return img, nil
+}
+
+// Snapshot returns the frame last drawn by Render, covering the bounds of
+// the renderer or the whole surface, with the padding around the grid. The
+// frame is rendered again from the last grid, so it can be taken outside
+// the window's redraw. It returns nil if nothing was rendered yet.
+func (r *Renderer) Snapshot() *image.RGBA {
+	if r.lastGrid == nil {
+		return nil
+	}
+
+	area := image.Rect(0, 0, int(r.lastWidth), int(r.lastHeight))
+	if r.boundsWidth > 0 && r.boundsHeight > 0 {
+		area = image.Rect(int(r.boundsX), int(r.boundsY),
+			int(r.boundsX+r.boundsWidth), int(r.boundsY+r.boundsHeight))
+	}
+	img := image.NewRGBA(image.Rect(0, 0, area.Dx(), area.Dy()))
+	fillImage(img, img.Bounds(), [3]byte{r.defaultBg.R, r.defaultBg.G, r.defaultBg.B})
+
+	cells, err := r.RenderImage(r.lastGrid)
+	if err != nil {
+		return img
+	}
+	origin := image.Pt(int(r.originX), int(r.originY)).Sub(area.Min)
+	draw.Draw(img, cells.Bounds().Add(origin), cells, image.Point{}, draw.Src)
+	return img
+}
+
+// fillImage fills a rectangle of an image with an opaque color.
+func fillImage(img *image.RGBA, rect image.Rectangle, rgb [3]byte) {
+	c := color.RGBA{rgb[0], rgb[1], rgb[2], 255}
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			img.SetRGBA(x, y, c)
+		}
+	}
+}
+
+// WritePNG encodes an image as PNG to a file, replacing it if it exists.
+func WritePNG(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to encode %s: %w", path, err)
+	}
+	return f.Close()
+}
+
+// ImageDiff is the result of DiffImages.
+type ImageDiff struct {
+	// Image shows the differing pixels in red over a faded copy of the
+	// expected image. Pixels outside one of the two images count as
+	// differing.
+	Image *image.RGBA
+
+	// Pixels is the number of differing pixels.
+	Pixels int
+
+	// MaxDelta is the largest difference of a color channel between the
+	// two images.
+	MaxDelta uint8
+}
+
+// Equal reports whether no pixel differs.
+func (d ImageDiff) Equal() bool {
+	return d.Pixels == 0
+}
+
+// DiffImages compares two images pixel by pixel. A pixel differs when one
+// of its color or alpha channels differs by more than tolerance, in 8-bit
+// steps. Images of different sizes are compared over their union.
+func DiffImages(want, got image.Image, tolerance uint8) ImageDiff {
+	wb, gb := want.Bounds(), got.Bounds()
+	bounds := wb.Union(gb)
+	diff := ImageDiff{Image: image.NewRGBA(bounds)}
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			p := image.Pt(x, y)
+			inWant, inGot := p.In(wb), p.In(gb)
+
+			var w color.RGBA
+			if inWant {
+				w = color.RGBAModel.Convert(want.At(x, y)).(color.RGBA)
+			}
+			delta := uint8(255)
+			if inWant && inGot {
+				g := color.RGBAModel.Convert(got.At(x, y)).(color.RGBA)
+				delta = max(absDiff(w.R, g.R), absDiff(w.G, g.G), absDiff(w.B, g.B), absDiff(w.A, g.A))
+			}
+			diff.MaxDelta = max(diff.MaxDelta, delta)
+
+			if !inWant || !inGot || delta > tolerance {
+				diff.Pixels++
+				diff.Image.SetRGBA(x, y, color.RGBA{255, 0, 0, 255})
+				continue
+			}
+			// Matching pixels are faded to gray so the differences stand out
+			gray := uint8((uint16(w.R) + uint16(w.G) + uint16(w.B)) / 3)
+			gray = 192 + gray/4
+			diff.Image.SetRGBA(x, y, color.RGBA{gray, gray, gray, 255})
+		}
+	}
+	return diff
+}
+
+// absDiff returns the absolute difference of two channel values.
+func absDiff(a, b uint8) uint8 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
@@ -0,0 +1,83 @@
+package lib
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestRenderImage(t *testing.T) {
+	theme := Theme{Foreground: NewColor(200, 200, 200), Background: NewColor(10, 20, 30)}
+	grid := ParseANSI("A\x1b[41m \x1b[0m", 3, 2)
+
+	img, err := RenderImage(grid, theme, nil)
+	if err != nil {
+		t.Fatalf("RenderImage failed: %v", err)
+	}
+
+	font, _ := NewFont()
+	cw, ch := font.CellWidth(), font.CellHeight()
+	if want := image.Rect(0, 0, 3*cw, 2*ch); img.Bounds() != want {
+		t.Fatalf("Bounds = %v, want %v", img.Bounds(), want)
+	}
+
+	// The glyph of 'A' uses the theme's foreground somewhere in its cell
+	foundFg := false
+	for y := 0; y < ch && !foundFg; y++ {
+		for x := 0; x < cw; x++ {
+			if img.RGBAAt(x, y) == (color.RGBA{200, 200, 200, 255}) {
+				foundFg = true
+				break
+			}
+		}
+	}
+	if !foundFg {
+		t.Error("Expected the glyph to use the theme foreground color")
+	}
+
+	// Spaces are filled with their background color
+	red := grid.Cells[0][1].BgColor
+	if got := img.RGBAAt(cw+cw/2, ch/2); got != (color.RGBA{red.R, red.G, red.B, 255}) {
+		t.Errorf("Cell with red background = %v", got)
+	}
+	if got := img.RGBAAt(cw/2, ch+ch/2); got != (color.RGBA{10, 20, 30, 255}) {
+		t.Errorf("Empty cell = %v, want the theme background", got)
+	}
+
+	if _, err := RenderImage(nil, theme, font); err == nil {
+		t.Error("Expected an error for a nil grid")
+	}
+}
+
+func TestDiffImages(t *testing.T) {
+	want := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	got := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			want.SetRGBA(x, y, color.RGBA{100, 100, 100, 255})
+			got.SetRGBA(x, y, color.RGBA{100, 100, 100, 255})
+		}
+	}
+	got.SetRGBA(1, 1, color.RGBA{103, 100, 100, 255})
+	got.SetRGBA(2, 2, color.RGBA{100, 150, 100, 255})
+
+	diff := DiffImages(want, got, 5)
+	if diff.Pixels != 1 || diff.MaxDelta != 50 || diff.Equal() {
+		t.Errorf("Pixels = %d, MaxDelta = %d, want 1 and 50", diff.Pixels, diff.MaxDelta)
+	}
+	if c := diff.Image.RGBAAt(2, 2); c != (color.RGBA{255, 0, 0, 255}) {
+		t.Errorf("Differing pixel shown as %v, want red", c)
+	}
+	if c := diff.Image.RGBAAt(1, 1); c.R != c.G || c.G != c.B {
+		t.Errorf("Pixel within tolerance shown as %v, want gray", c)
+	}
+
+	if !DiffImages(want, got, 50).Equal() {
+		t.Error("Expected images to be equal with a tolerance of 50")
+	}
+
+	larger := image.NewRGBA(image.Rect(0, 0, 5, 4))
+	if d := DiffImages(want, larger, 255); d.Pixels != 4 || d.Image.Bounds() != larger.Bounds() {
+		t.Errorf("Size mismatch: Pixels = %d, bounds %v; want 4 and %v", d.Pixels, d.Image.Bounds(), larger.Bounds())
+	}
+}
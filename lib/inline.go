@@ -0,0 +1,93 @@
+package lib
+
+import "strings"
+
+// measureView returns the number of columns and lines the view needs when
+// rendered. Escape sequences take no space, tabs advance to the next
+// multiple of 8, and a single trailing newline does not add an empty line.
+func measureView(view string) (cols, rows int) {
+	view = strings.TrimSuffix(view, "\n")
+	lines := strings.Split(view, "\n")
+	for _, line := range lines {
+		cols = max(cols, lineWidth(line))
+	}
+	return cols, len(lines)
+}
+
+// lineWidth returns the number of cells a single line of the view occupies,
+// following the cursor movement of ParseANSI for printable text. Escape
+// sequences take no space and every other rune one cell, wide East Asian
+// runes and emoji included, as the grid has one rune per cell.
+func lineWidth(line string) int {
+	runes := []rune(line)
+	x, width := 0, 0
+	for i := 0; i < len(runes); i++ {
+		switch runes[i] {
+		case '\x1b':
+			if i+1 < len(runes) && runes[i+1] == '[' {
+				// Skip to the final letter of the sequence
+				i += 2
+				for i < len(runes) && !isSequenceFinal(runes[i]) {
+					i++
+				}
+				continue
+			}
+			x++
+		case '\r':
+			x = 0
+		case '\t':
+			x = (x/8 + 1) * 8
+		default:
+			x++
+		}
+		width = max(width, x)
+	}
+	return width
+}
+
+// isSequenceFinal reports whether r ends an escape sequence.
+func isSequenceFinal(r rune) bool {
+	return (r >= 'A' && r <= 'Z') || (r >= 'a' && r <= 'z')
+}
+
+// fitToContent resizes the window toward the size the view needs in inline
+// mode. The first fit is applied at once; later changes move the window
+// part of the way each frame, so the window grows and shrinks smoothly.
+// Must be called with p.mu held.
+func (p *Program) fitToContent(view string) {
+	if p.widget == nil || p.renderer == nil || p.pixelWidth == 0 || p.windowState.Fullscreen || p.windowState.Maximized {
+		return
+	}
+
+	cols, rows := measureView(view)
+	cols, rows = p.clampCells(max(cols, 1), max(rows, 1))
+	targetWidth, targetHeight := windowSizeFor(cols, rows,
+		p.renderer.CellWidth(), p.renderer.CellHeight(), p.options.Padding)
+	if !p.options.InlineWidth {
+		targetWidth = p.pixelWidth
+	}
+
+	width, height := targetWidth, targetHeight
+	if p.inlineSized {
+		width = p.pixelWidth + resizeStep(targetWidth-p.pixelWidth)
+		height = p.pixelHeight + resizeStep(targetHeight-p.pixelHeight)
+	}
+	if width == p.pixelWidth && height == p.pixelHeight {
+		return
+	}
+
+	if p.requestWindowSize(width, height) {
+		p.inlineSized = true
+		p.pixelWidth, p.pixelHeight = width, height
+	}
+}
+
+// resizeStep returns how far the window moves toward its target size in one
+// frame: half the remaining distance, and at least one pixel.
+func resizeStep(diff int32) int32 {
+	step := diff / 2
+	if step == 0 {
+		return diff
+	}
+	return step
+}
@@ -0,0 +1,111 @@
+package lib
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMeasureView(t *testing.T) {
+	tests := []struct {
+		name       string
+		view       string
+		cols, rows int
+	}{
+		{"empty", "", 0, 1},
+		{"single line", "Continue? [y/n]", 15, 1},
+		{"trailing newline", "one\ntwo\n", 3, 2},
+		{"widest line", "a\nabcdef\nabc", 6, 3},
+		{"styled", "\x1b[1;31mred\x1b[0m text", 8, 1},
+		{"zones", Mark("ok", "[ OK ]"), 6, 1},
+		{"tab", "a\tb", 9, 1},
+		{"unicode", "héllo", 5, 1},
+		{"wide", "日本語 ok", 6, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cols, rows := measureView(tt.view)
+			if cols != tt.cols || rows != tt.rows {
+				t.Errorf("measureView(%q) = (%d, %d), want (%d, %d)", tt.view, cols, rows, tt.cols, tt.rows)
+			}
+		})
+	}
+}
+
+// TestMeasureView_FitsRender tests that a view rendered at its measured size
+// shows every line on its own row, without wrapping.
+func TestMeasureView_FitsRender(t *testing.T) {
+	lines := []string{"Continue?", "\x1b[1m[y/n]\x1b[0m", Mark("ok", "[ OK ]"), "Cancel", "abcdefghi"}
+	view := strings.Join(lines, "\n")
+
+	cols, rows := measureView(view)
+	grid := ParseANSI(view, cols, rows)
+	for y, line := range []string{"Continue?", "[y/n]", "[ OK ]", "Cancel", "abcdefghi"} {
+		var row strings.Builder
+		for x := 0; x < grid.Width; x++ {
+			row.WriteRune(grid.Cells[y][x].Rune)
+		}
+		if got := strings.TrimRight(row.String(), " "); got != line {
+			t.Errorf("row %d = %q, want %q", y, got, line)
+		}
+	}
+}
+
+// TestMeasureView_WideText tests that wide runes are measured as the parser
+// lays them out, one per cell, so wide text neither wraps nor leaves empty
+// columns.
+func TestMeasureView_WideText(t *testing.T) {
+	view := "日本語 ok\n😀 hi"
+	cols, rows := measureView(view)
+	grid := ParseANSI(view, cols, rows)
+	for y, line := range []string{"日本語 ok", "😀 hi"} {
+		var row strings.Builder
+		for x := 0; x < grid.Width; x++ {
+			row.WriteRune(grid.Cells[y][x].Rune)
+		}
+		if got := strings.TrimRight(row.String(), " "); got != line {
+			t.Errorf("row %d = %q, want %q", y, got, line)
+		}
+	}
+	if last := grid.Cells[0][cols-1].Rune; last != 'k' {
+		t.Errorf("widest line ends with %q in the last column, want 'k'", last)
+	}
+}
+
+func TestResizeStep(t *testing.T) {
+	tests := []struct {
+		diff, want int32
+	}{
+		{100, 50},
+		{-100, -50},
+		{3, 1},
+		{1, 1},
+		{-1, -1},
+		{0, 0},
+	}
+
+	for _, tt := range tests {
+		if got := resizeStep(tt.diff); got != tt.want {
+			t.Errorf("resizeStep(%d) = %d, want %d", tt.diff, got, tt.want)
+		}
+	}
+
+	// Repeated steps reach the target
+	pos, target := int32(600), int32(48)
+	for i := 0; i < 20 && pos != target; i++ {
+		pos += resizeStep(target - pos)
+	}
+	if pos != target {
+		t.Errorf("smooth resize stopped at %d, want %d", pos, target)
+	}
+}
+
+func TestSizeLimitOptions(t *testing.T) {
+	p := NewProgram(nil, WithInline(true), WithMinSize(20, 1), WithMaxSize(60, 10))
+	if !p.options.Inline || !p.options.InlineWidth {
+		t.Error("WithInline(true) did not enable inline width")
+	}
+	if cols, rows := p.clampCells(5, 30); cols != 20 || rows != 10 {
+		t.Errorf("clampCells(5, 30) = (%d, %d), want (20, 10)", cols, rows)
+	}
+}
@@ -113,6 +113,8 @@ func mapSpecialKey(keysym uint32, mods window.ModType) (KeyType, bool) {
 		return KeyF11, true
 	case xkbcommon.KeyF12:
 		return KeyF12, true
+	case keyPrintScreen:
+		return KeyPrintScreen, true
 	}
 
 	return KeyRunes, false
@@ -34,6 +34,7 @@ func TestMapSpecialKey(t *testing.T) {
 		{"F1", xkbcommon.KeyF1, 0, KeyF1, true},
 		{"F2", xkbcommon.KeyF2, 0, KeyF2, true},
 		{"F12", xkbcommon.KeyF12, 0, KeyF12, true},
+		{"Print Screen", keyPrintScreen, 0, KeyPrintScreen, true},
 		{"Regular key", 'a', 0, KeyRunes, false},
 	}
 
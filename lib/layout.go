@@ -0,0 +1,60 @@
+package lib
+
+// ContentPlacement specifies where the grid is placed in the window when the
+// window is not an exact multiple of the cell size.
+type ContentPlacement int
+
+const (
+	// PlacementTopLeft places the grid in the top-left corner, leaving the
+	// leftover space at the right and bottom edges.
+	PlacementTopLeft ContentPlacement = iota
+
+	// PlacementCenter centers the grid, splitting the leftover space
+	// between opposite edges.
+	PlacementCenter
+)
+
+// String returns a string representation of the placement.
+func (c ContentPlacement) String() string {
+	switch c {
+	case PlacementTopLeft:
+		return "top-left"
+	case PlacementCenter:
+		return "center"
+	default:
+		return "unknown"
+	}
+}
+
+// layout is the position and size of the grid in a window.
+type layout struct {
+	cols    int
+	rows    int
+	originX int32
+	originY int32
+}
+
+// computeLayout fits the grid into a window of the given pixel size, leaving
+// padding pixels free on every edge, and places it according to placement.
+func computeLayout(width, height, cellWidth, cellHeight, padding int32, placement ContentPlacement) layout {
+	availWidth := max(width-2*padding, 0)
+	availHeight := max(height-2*padding, 0)
+
+	l := layout{
+		cols:    int(availWidth / cellWidth),
+		rows:    int(availHeight / cellHeight),
+		originX: padding,
+		originY: padding,
+	}
+	if placement == PlacementCenter {
+		l.originX += (availWidth - int32(l.cols)*cellWidth) / 2
+		l.originY += (availHeight - int32(l.rows)*cellHeight) / 2
+	}
+	return l
+}
+
+// windowSizeFor returns the pixel size of a window that fits exactly the
+// given number of cells plus padding on every edge.
+func windowSizeFor(cols, rows int, cellWidth, cellHeight, padding int32) (int32, int32) {
+	return int32(cols)*cellWidth + 2*padding, int32(rows)*cellHeight + 2*padding
+}
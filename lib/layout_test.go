@@ -0,0 +1,60 @@
+package lib
+
+import "testing"
+
+func TestComputeLayout(t *testing.T) {
+	tests := []struct {
+		name          string
+		width, height int32
+		padding       int32
+		placement     ContentPlacement
+		want          layout
+	}{
+		{"exact fit", 80, 40, 0, PlacementTopLeft, layout{cols: 10, rows: 2}},
+		{"leftover top-left", 85, 47, 0, PlacementTopLeft, layout{cols: 10, rows: 2}},
+		{"leftover centered", 85, 47, 0, PlacementCenter, layout{cols: 10, rows: 2, originX: 2, originY: 3}},
+		{"padding", 100, 60, 10, PlacementTopLeft, layout{cols: 10, rows: 2, originX: 10, originY: 10}},
+		{"padding centered", 104, 60, 10, PlacementCenter, layout{cols: 10, rows: 2, originX: 12, originY: 10}},
+		{"too small", 10, 10, 8, PlacementCenter, layout{originX: 8, originY: 8}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := computeLayout(tt.width, tt.height, 8, 20, tt.padding, tt.placement)
+			if got != tt.want {
+				t.Errorf("computeLayout() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWindowSizeFor(t *testing.T) {
+	width, height := windowSizeFor(80, 24, 8, 16, 4)
+	if width != 648 || height != 392 {
+		t.Errorf("windowSizeFor() = %dx%d, want 648x392", width, height)
+	}
+}
+
+func TestGridPoint(t *testing.T) {
+	p := NewProgram(nil)
+	renderer, err := NewRenderer(RendererOptions{})
+	if err != nil {
+		t.Fatalf("NewRenderer() error = %v", err)
+	}
+	p.renderer = renderer
+	p.windowWidth, p.windowHeight = 10, 5
+	p.originX, p.originY = 6, 4
+
+	cellWidth := float32(renderer.CellWidth())
+	cellHeight := float32(renderer.CellHeight())
+
+	if x, y := p.cellAt(6+cellWidth*2, 4+cellHeight); x != 2 || y != 1 {
+		t.Errorf("cellAt() inside grid = (%d, %d), want (2, 1)", x, y)
+	}
+	if x, y := p.cellAt(1, 1); x != 0 || y != 0 {
+		t.Errorf("cellAt() in padding = (%d, %d), want (0, 0)", x, y)
+	}
+	if x, y := p.cellAt(6+cellWidth*20, 4+cellHeight*20); x != 9 || y != 4 {
+		t.Errorf("cellAt() past the grid = (%d, %d), want (9, 4)", x, y)
+	}
+}
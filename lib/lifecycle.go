@@ -0,0 +1,120 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrProgramKilled is returned by Run when the program was stopped with
+// Kill or by cancelling the context set with WithContext.
+var ErrProgramKilled = errors.New("program was killed")
+
+// PanicError is returned by Run when Init, Update or View panicked.
+type PanicError struct {
+	// Value is the value passed to panic.
+	Value interface{}
+
+	// Stack is the stack trace of the goroutine that panicked.
+	Stack string
+}
+
+// Error returns a description of the panic.
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("program panicked: %v", e.Value)
+}
+
+// Kill stops the program immediately, without waiting for running commands
+// to finish. Run returns ErrProgramKilled. It can be called from any
+// goroutine and more than once.
+func (p *Program) Kill() {
+	p.killed.Store(true)
+	p.cancel()
+	p.Quit()
+}
+
+// Wait blocks until Run returns, or until an embedded program is detached.
+func (p *Program) Wait() {
+	<-p.done
+}
+
+// markDone unblocks Wait.
+func (p *Program) markDone() {
+	p.doneOnce.Do(func() {
+		close(p.done)
+	})
+}
+
+// recordPanic keeps the first panic of Init, Update or View to be returned
+// from Run.
+func (p *Program) recordPanic(value interface{}, stack string) {
+	if p.panicErr == nil {
+		p.panicErr = &PanicError{Value: value, Stack: stack}
+	}
+}
+
+// wasKilled reports whether the program was stopped with Kill or by its
+// parent context.
+func (p *Program) wasKilled() bool {
+	if p.killed.Load() {
+		return true
+	}
+	return p.options.Context != nil && p.options.Context.Err() != nil
+}
+
+// exitError returns the error Run returns once the display loop ended.
+func (p *Program) exitError() error {
+	if p.panicErr != nil {
+		return p.panicErr
+	}
+	if p.killed.Load() {
+		return ErrProgramKilled
+	}
+	if p.wasKilled() {
+		return fmt.Errorf("%w: %w", ErrProgramKilled, p.options.Context.Err())
+	}
+	return nil
+}
+
+// shutdownCommands waits for running commands to finish, unless the program
+// was killed. With a shutdown timeout set, it returns a *LeakedCommandsError
+// if commands were still running when it expired.
+func (p *Program) shutdownCommands() error {
+	if p.wasKilled() {
+		Debug("Program killed, not waiting for running commands")
+		return nil
+	}
+	return p.cmdExec.ShutdownTimeout(p.options.ShutdownTimeout)
+}
+
+// watchExit ends the display loop when Quit or Kill is called from another
+// goroutine or the parent context is cancelled. The returned function stops
+// watching and must be called before the display is destroyed.
+func (p *Program) watchExit() (stop func()) {
+	stopCh := make(chan struct{})
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		select {
+		case <-p.quitChan:
+		case <-p.ctx.Done():
+			// The program cancels its own context when it quits on the
+			// display thread; only a cancelled parent needs a wakeup
+			if !p.wasKilled() {
+				return
+			}
+		case <-stopCh:
+			return
+		}
+
+		Debug("Exit requested, waking the display loop")
+		p.wakeDisplay(p.quit)
+	}()
+
+	return func() {
+		close(stopCh)
+		wg.Wait()
+	}
+}
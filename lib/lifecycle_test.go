@@ -0,0 +1,148 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// panicModel panics in Init.
+type panicModel struct{}
+
+func (m panicModel) Init() Cmd                   { panic("init failed") }
+func (m panicModel) Update(msg Msg) (Model, Cmd) { return m, nil }
+func (m panicModel) View() string                { return "" }
+
+func TestProgram_QuitIsIdempotent(t *testing.T) {
+	p := NewProgram(nil)
+	p.Quit()
+	p.Quit()
+
+	select {
+	case <-p.quitChan:
+	default:
+		t.Fatal("Quit did not signal the display loop")
+	}
+
+	// The next frame ends the loop
+	p.Redraw(nil)
+	if p.ctx.Err() == nil {
+		t.Error("program context not cancelled after Quit")
+	}
+	if err := p.exitError(); err != nil {
+		t.Errorf("exitError() after Quit = %v, want nil", err)
+	}
+}
+
+func TestProgram_Kill(t *testing.T) {
+	p := NewProgram(nil)
+	p.Kill()
+	p.Kill()
+
+	if p.ctx.Err() == nil {
+		t.Error("program context not cancelled after Kill")
+	}
+	if !p.wasKilled() {
+		t.Error("wasKilled() = false after Kill")
+	}
+	if err := p.exitError(); err != ErrProgramKilled {
+		t.Errorf("exitError() = %v, want ErrProgramKilled", err)
+	}
+}
+
+func TestProgram_WithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := NewProgram(nil, WithContext(ctx))
+
+	if p.wasKilled() {
+		t.Fatal("program killed before the context was cancelled")
+	}
+	cancel()
+
+	select {
+	case <-p.ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("program context not cancelled with its parent")
+	}
+	err := p.exitError()
+	if !errors.Is(err, ErrProgramKilled) || !errors.Is(err, context.Canceled) {
+		t.Errorf("exitError() = %v, want ErrProgramKilled wrapping context.Canceled", err)
+	}
+}
+
+func TestProgram_PanicError(t *testing.T) {
+	p := NewProgram(panicModel{})
+	p.startModel()
+
+	var panicErr *PanicError
+	if err := p.exitError(); !errors.As(err, &panicErr) {
+		t.Fatalf("exitError() = %v, want a PanicError", err)
+	}
+	if panicErr.Value != "init failed" {
+		t.Errorf("panic value = %v, want %q", panicErr.Value, "init failed")
+	}
+	if !strings.Contains(panicErr.Stack, "panicModel.Init") {
+		t.Errorf("stack does not show the panicking function:\n%s", panicErr.Stack)
+	}
+	if p.ctx.Err() == nil {
+		t.Error("program still running after Init panicked")
+	}
+
+	// Later panics do not replace the first one
+	p.recordPanic("later", "")
+	if p.panicErr.Value != "init failed" {
+		t.Errorf("first panic was replaced by %v", p.panicErr.Value)
+	}
+}
+
+func TestProgram_Wait(t *testing.T) {
+	p := NewProgram(nil)
+
+	waited := make(chan struct{})
+	go func() {
+		p.Wait()
+		close(waited)
+	}()
+
+	select {
+	case <-waited:
+		t.Fatal("Wait returned before the program finished")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	p.markDone()
+	p.markDone()
+	select {
+	case <-waited:
+	case <-time.After(time.Second):
+		t.Fatal("Wait did not return after the program finished")
+	}
+}
+
+// TestProgram_ShutdownWaitsByDefault tests that without WithShutdownTimeout
+// the program waits for running commands, however long they take.
+func TestProgram_ShutdownWaitsByDefault(t *testing.T) {
+	p := NewProgram(nil)
+	if p.options.ShutdownTimeout != 0 {
+		t.Fatalf("default ShutdownTimeout = %v, want 0", p.options.ShutdownTimeout)
+	}
+	p.cmdExec = NewCommandExecutor(p.ctx, p.msgChan)
+
+	finished := make(chan struct{})
+	p.cmdExec.Execute(func() Msg {
+		time.Sleep(100 * time.Millisecond)
+		close(finished)
+		return nil
+	})
+
+	if err := p.shutdownCommands(); err != nil {
+		t.Fatalf("shutdownCommands() = %v, want nil", err)
+	}
+	select {
+	case <-finished:
+	default:
+		t.Error("shutdownCommands returned before the command finished")
+	}
+}
@@ -37,6 +37,7 @@ const (
 	KeyCtrlD
 	KeyCtrlL
 	KeyCtrlZ
+	KeyPrintScreen
 )
 
 // KeyMsg represents a keyboard input event.
@@ -84,25 +85,252 @@ type MouseMsg struct {
 	Y      int
 	Type   MouseEventType
 	Button MouseButton
+
+	// ClickCount is the number of consecutive clicks at the same position
+	// for press and release events: 1 for a single click, 2 for a double
+	// click, 3 for a triple click and so on. It is 0 for other events.
+	ClickCount int
+
+	// Steps is the number of lines to scroll for wheel events. Touchpad and
+	// high-resolution wheel motion is accumulated into whole steps, so it is
+	// always at least 1 for wheel events and 0 for other events.
+	Steps int
+
+	// Zones lists the IDs of the zones marked with Mark that are under the
+	// pointer, innermost first.
+	Zones []string
 }
 
 // String returns a string representation of the mouse message for debugging.
 func (m MouseMsg) String() string {
-	return fmt.Sprintf("MouseMsg{X: %d, Y: %d, Type: %v, Button: %v}", m.X, m.Y, m.Type, m.Button)
+	return fmt.Sprintf("MouseMsg{X: %d, Y: %d, Type: %v, Button: %v, ClickCount: %d, Steps: %d, Zones: %v}",
+		m.X, m.Y, m.Type, m.Button, m.ClickCount, m.Steps, m.Zones)
+}
+
+// IsDoubleClick reports whether the event is the second click of a series.
+func (m MouseMsg) IsDoubleClick() bool {
+	return m.ClickCount == 2
+}
+
+// IsTripleClick reports whether the event is the third click of a series.
+func (m MouseMsg) IsTripleClick() bool {
+	return m.ClickCount == 3
+}
+
+// InZone reports whether the pointer is over the zone with the given ID.
+func (m MouseMsg) InZone(id string) bool {
+	for _, zone := range m.Zones {
+		if zone == id {
+			return true
+		}
+	}
+	return false
+}
+
+// TouchEventType represents the type of touch event.
+type TouchEventType int
+
+const (
+	TouchDown TouchEventType = iota
+	TouchMotion
+	TouchUp
+	TouchCancel
+)
+
+// TouchMsg represents a touch point event. Each finger on the screen has its
+// own ID for as long as it stays down.
+type TouchMsg struct {
+	ID   int
+	X    int
+	Y    int
+	Type TouchEventType
+}
+
+// String returns a string representation of the touch message for debugging.
+func (t TouchMsg) String() string {
+	return fmt.Sprintf("TouchMsg{ID: %d, X: %d, Y: %d, Type: %v}", t.ID, t.X, t.Y, t.Type)
+}
+
+// GestureType represents the type of a recognized touch gesture.
+type GestureType int
+
+const (
+	GestureTap GestureType = iota
+	GestureLongPress
+	GestureSwipe
+	GesturePinch
+)
+
+// GestureMsg represents a recognized touch gesture.
+type GestureMsg struct {
+	Type GestureType
+
+	// X and Y are the cell where the gesture started. For two-finger
+	// gestures this is the point between the fingers.
+	X int
+	Y int
+
+	// DX and DY are the distance in cells travelled by a swipe.
+	DX int
+	DY int
+
+	// Scale is the ratio of the final to the initial finger distance of a
+	// pinch: below 1 for pinching in, above 1 for spreading out.
+	Scale float64
+
+	// Fingers is the number of fingers involved in the gesture.
+	Fingers int
+}
+
+// String returns a string representation of the gesture message for debugging.
+func (g GestureMsg) String() string {
+	return fmt.Sprintf("GestureMsg{Type: %v, X: %d, Y: %d, DX: %d, DY: %d, Scale: %.2f, Fingers: %d}",
+		g.Type, g.X, g.Y, g.DX, g.DY, g.Scale, g.Fingers)
 }
 
 // WindowSizeMsg represents a window resize event.
+// Width and Height are the size of the grid in cells.
 type WindowSizeMsg struct {
 	Width  int
 	Height int
+
+	// PixelWidth and PixelHeight are the size of the window in pixels,
+	// including padding and leftover space around the grid.
+	PixelWidth  int
+	PixelHeight int
+
+	// CellWidth and CellHeight are the size of a cell in pixels.
+	CellWidth  int
+	CellHeight int
 }
 
 // String returns a string representation of the window size message for debugging.
 func (w WindowSizeMsg) String() string {
-	return fmt.Sprintf("WindowSizeMsg{Width: %d, Height: %d}", w.Width, w.Height)
+	return fmt.Sprintf("WindowSizeMsg{Width: %d, Height: %d, PixelWidth: %d, PixelHeight: %d, CellWidth: %d, CellHeight: %d}",
+		w.Width, w.Height, w.PixelWidth, w.PixelHeight, w.CellWidth, w.CellHeight)
+}
+
+// WindowStateMsg is sent when the state of the window changes.
+type WindowStateMsg struct {
+	// Fullscreen reports whether the window covers the whole screen.
+	Fullscreen bool
+
+	// Maximized reports whether the window is maximized.
+	Maximized bool
+
+	// Activated reports whether the window is the active window, the one
+	// receiving keyboard input.
+	Activated bool
+
+	// Suspended reports whether the window is not visible to the user, so
+	// drawing can be paused.
+	Suspended bool
+}
+
+// String returns a string representation of the window state message for debugging.
+func (w WindowStateMsg) String() string {
+	return fmt.Sprintf("WindowStateMsg{Fullscreen: %v, Maximized: %v, Activated: %v, Suspended: %v}",
+		w.Fullscreen, w.Maximized, w.Activated, w.Suspended)
+}
+
+// FocusMsg is sent when the window gains keyboard focus.
+// It is only sent when focus reporting is enabled with WithReportFocus.
+// This matches Bubble Tea's FocusMsg for compatibility.
+type FocusMsg struct{}
+
+// String returns a string representation of the focus message for debugging.
+func (f FocusMsg) String() string {
+	return "FocusMsg{}"
+}
+
+// BlurMsg is sent when the window loses keyboard focus.
+// It is only sent when focus reporting is enabled with WithReportFocus.
+// This matches Bubble Tea's BlurMsg for compatibility.
+type BlurMsg struct{}
+
+// String returns a string representation of the blur message for debugging.
+func (b BlurMsg) String() string {
+	return "BlurMsg{}"
+}
+
+// MouseEnterMsg is sent when the pointer enters the window.
+type MouseEnterMsg struct {
+	X int
+	Y int
+}
+
+// String returns a string representation of the mouse enter message for debugging.
+func (m MouseEnterMsg) String() string {
+	return fmt.Sprintf("MouseEnterMsg{X: %d, Y: %d}", m.X, m.Y)
+}
+
+// MouseLeaveMsg is sent when the pointer leaves the window.
+type MouseLeaveMsg struct{}
+
+// String returns a string representation of the mouse leave message for debugging.
+func (m MouseLeaveMsg) String() string {
+	return "MouseLeaveMsg{}"
+}
+
+// DragEnterMsg is sent when something is dragged into the window.
+// MIME lists the formats the drag source offers.
+type DragEnterMsg struct {
+	MIME []string
+	X    int
+	Y    int
+}
+
+// String returns a string representation of the drag enter message for debugging.
+func (d DragEnterMsg) String() string {
+	return fmt.Sprintf("DragEnterMsg{MIME: %v, X: %d, Y: %d}", d.MIME, d.X, d.Y)
+}
+
+// DragLeaveMsg is sent when a drag leaves the window without being dropped.
+type DragLeaveMsg struct{}
+
+// String returns a string representation of the drag leave message for debugging.
+func (d DragLeaveMsg) String() string {
+	return "DragLeaveMsg{}"
+}
+
+// WindowClosedMsg is sent to the model that opened a secondary window with
+// OpenWindow when that window closes. Err is set if the window could not be
+// opened.
+type WindowClosedMsg struct {
+	ID  WindowID
+	Err error
+}
+
+// String returns a string representation of the window closed message for debugging.
+func (w WindowClosedMsg) String() string {
+	if w.Err != nil {
+		return fmt.Sprintf("WindowClosedMsg{ID: %d, Err: %v}", w.ID, w.Err)
+	}
+	return fmt.Sprintf("WindowClosedMsg{ID: %d}", w.ID)
+}
+
+// DropMsg is sent when files or text are dropped onto the window.
+// For a list of URIs, URIs holds them as dropped and Paths holds the local
+// file paths of the file:// URIs among them. Text holds the dropped data
+// as text in every case.
+type DropMsg struct {
+	MIME  string
+	Paths []string
+	URIs  []string
+	Text  string
+	X     int
+	Y     int
+}
+
+// String returns a string representation of the drop message for debugging.
+func (d DropMsg) String() string {
+	return fmt.Sprintf("DropMsg{MIME: %q, Paths: %v, URIs: %v, Text: %q, X: %d, Y: %d}",
+		d.MIME, d.Paths, d.URIs, d.Text, d.X, d.Y)
 }
 
-// QuitMsg represents a termination signal for the application.
+// QuitMsg represents a termination signal for the application. It is
+// returned by the Quit command and passes through the filters set with
+// WithFilter, which can veto quitting by returning nil.
 type QuitMsg struct{}
 
 // String returns a string representation of the quit message for debugging.
@@ -0,0 +1,118 @@
+package lib
+
+// UpdateFunc has the signature of a model's Update method, with the model
+// passed explicitly.
+type UpdateFunc func(m Model, msg Msg) (Model, Cmd)
+
+// ViewFunc has the signature of a model's View method, with the model
+// passed explicitly.
+type ViewFunc func(m Model) string
+
+// Middleware wraps the Update and View functions of the model, for concerns
+// shared by every model of an application such as logging, global shortcuts
+// or an overlay. Each function receives the next function in the chain and
+// returns its replacement. Either field may be nil.
+type Middleware struct {
+	Update func(next UpdateFunc) UpdateFunc
+	View   func(next ViewFunc) ViewFunc
+}
+
+// WithFilter adds a function that intercepts every message before Update.
+// It returns the message to deliver, a different message, or nil to drop
+// it. Quit commands send QuitMsg through the filters, so a filter can veto
+// quitting, for example to ask for confirmation first. Program.Quit and
+// Program.Kill are not filtered. Filters run in the order they were added,
+// right before the message is delivered.
+// This matches Bubble Tea's WithFilter option for compatibility.
+func WithFilter(filter func(Model, Msg) Msg) ProgramOption {
+	return func(opts *ProgramOptions) {
+		opts.Filters = append(opts.Filters, filter)
+	}
+}
+
+// WithMiddleware adds middleware around the model's Update and View. The
+// first middleware added is the outermost one: it sees a message first and
+// the rendered view last.
+func WithMiddleware(mw ...Middleware) ProgramOption {
+	return func(opts *ProgramOptions) {
+		opts.Middleware = append(opts.Middleware, mw...)
+	}
+}
+
+// chainMiddleware returns the Update and View functions of a model wrapped
+// in the given middleware.
+func chainMiddleware(mw []Middleware) (UpdateFunc, ViewFunc) {
+	update := func(m Model, msg Msg) (Model, Cmd) {
+		return m.Update(msg)
+	}
+	view := func(m Model) string {
+		return m.View()
+	}
+
+	for i := len(mw) - 1; i >= 0; i-- {
+		if mw[i].Update != nil {
+			update = mw[i].Update(update)
+		}
+		if mw[i].View != nil {
+			view = mw[i].View(view)
+		}
+	}
+	return update, view
+}
+
+// filter passes msg through the filters of the program and returns the
+// message to deliver, or nil if a filter dropped it. Must be called with
+// p.mu held.
+func (p *Program) filter(msg Msg) (out Msg) {
+	defer func() {
+		if r := recover(); r != nil {
+			Error("Panic in filter: %v", r)
+			stack := getStackTrace()
+			Error("Stack trace: %v", stack)
+			p.recordPanic(r, stack)
+			p.quit()
+			out = nil
+		}
+	}()
+
+	for _, f := range p.options.Filters {
+		if msg == nil {
+			return nil
+		}
+		msg = f(p.model, msg)
+	}
+	return msg
+}
+
+// update delivers a filtered message to the model through the middleware
+// and executes the returned command. A nil message is ignored and QuitMsg
+// quits the program. Must be called with p.mu held.
+func (p *Program) update(msg Msg) {
+	if msg == nil {
+		return
+	}
+	if _, isQuit := msg.(QuitMsg); isQuit {
+		p.quit()
+		return
+	}
+
+	// Call Update with panic recovery
+	defer func() {
+		if r := recover(); r != nil {
+			Error("Panic in Update(): %v", r)
+			stack := getStackTrace()
+			Error("Stack trace: %v", stack)
+			p.recordPanic(r, stack)
+			// Exit gracefully on panic
+			p.quit()
+		}
+	}()
+
+	var cmd Cmd
+	p.model, cmd = p.updateFn(p.model, msg)
+
+	// Execute the returned command
+	if cmd != nil {
+		p.cmdExec.Execute(cmd)
+	}
+}
@@ -0,0 +1,173 @@
+package lib
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// logModel records the messages it receives.
+type logModel struct {
+	got []Msg
+}
+
+func (m logModel) Init() Cmd { return nil }
+func (m logModel) Update(msg Msg) (Model, Cmd) {
+	m.got = append(m.got, msg)
+	return m, nil
+}
+func (m logModel) View() string { return "view" }
+
+func TestChainMiddleware(t *testing.T) {
+	var calls []string
+	mw := func(name string) Middleware {
+		return Middleware{
+			Update: func(next UpdateFunc) UpdateFunc {
+				return func(m Model, msg Msg) (Model, Cmd) {
+					calls = append(calls, name)
+					return next(m, msg)
+				}
+			},
+			View: func(next ViewFunc) ViewFunc {
+				return func(m Model) string {
+					return "[" + name + " " + next(m) + "]"
+				}
+			},
+		}
+	}
+
+	update, view := chainMiddleware([]Middleware{mw("outer"), {}, mw("inner")})
+
+	m, _ := update(logModel{}, FocusMsg{})
+	if want := []string{"outer", "inner"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("Update order = %v, want %v", calls, want)
+	}
+	if got := m.(logModel).got; len(got) != 1 || got[0] != (FocusMsg{}) {
+		t.Errorf("model received %v, want [FocusMsg{}]", got)
+	}
+	if got, want := view(m), "[outer [inner view]]"; got != want {
+		t.Errorf("View = %q, want %q", got, want)
+	}
+}
+
+func TestProgram_Filter(t *testing.T) {
+	p := NewProgram(logModel{},
+		WithFilter(func(m Model, msg Msg) Msg {
+			// Turn Ctrl+C into a quit, like a global shortcut
+			if k, ok := msg.(KeyMsg); ok && k.Type == KeyCtrlC {
+				return QuitMsg{}
+			}
+			return msg
+		}),
+		WithFilter(func(m Model, msg Msg) Msg {
+			// Drop blur messages
+			if _, ok := msg.(BlurMsg); ok {
+				return nil
+			}
+			return msg
+		}),
+	)
+
+	if got := p.filter(FocusMsg{}); got != (FocusMsg{}) {
+		t.Errorf("filter(FocusMsg) = %v, want it unchanged", got)
+	}
+	if got := p.filter(BlurMsg{}); got != nil {
+		t.Errorf("filter(BlurMsg) = %v, want nil", got)
+	}
+	if got := p.filter(KeyMsg{Type: KeyCtrlC}); got != (QuitMsg{}) {
+		t.Errorf("filter(Ctrl+C) = %v, want QuitMsg", got)
+	}
+}
+
+func TestProgram_FilterVetoesQuit(t *testing.T) {
+	confirmed := false
+	p := NewProgram(logModel{}, WithFilter(func(m Model, msg Msg) Msg {
+		if _, ok := msg.(QuitMsg); ok && !confirmed {
+			return nil
+		}
+		return msg
+	}))
+	p.cmdExec = NewCommandExecutor(p.ctx, p.msgChan)
+
+	p.update(p.filter(QuitMsg{}))
+	if p.ctx.Err() != nil {
+		t.Fatal("Program quit although the filter vetoed it")
+	}
+
+	confirmed = true
+	p.update(p.filter(QuitMsg{}))
+	if p.ctx.Err() == nil {
+		t.Error("Program did not quit once the filter let QuitMsg through")
+	}
+}
+
+func TestProgram_UpdateThroughMiddleware(t *testing.T) {
+	var seen []Msg
+	p := NewProgram(logModel{}, WithMiddleware(Middleware{
+		Update: func(next UpdateFunc) UpdateFunc {
+			return func(m Model, msg Msg) (Model, Cmd) {
+				seen = append(seen, msg)
+				return next(m, msg)
+			}
+		},
+		View: func(next ViewFunc) ViewFunc {
+			return func(m Model) string {
+				return strings.ToUpper(next(m))
+			}
+		},
+	}))
+	p.cmdExec = NewCommandExecutor(p.ctx, p.msgChan)
+	defer p.cmdExec.Shutdown()
+
+	p.update(FocusMsg{})
+	p.update(nil)
+
+	if len(seen) != 1 || seen[0] != (FocusMsg{}) {
+		t.Errorf("middleware saw %v, want [FocusMsg{}]", seen)
+	}
+	if got := p.model.(logModel).got; len(got) != 1 {
+		t.Errorf("model received %v, want one message", got)
+	}
+	if got := p.viewFn(p.model); got != "VIEW" {
+		t.Errorf("View = %q, want %q", got, "VIEW")
+	}
+}
+
+func TestProgram_FilterPanic(t *testing.T) {
+	p := NewProgram(logModel{}, WithFilter(func(m Model, msg Msg) Msg {
+		panic("filter failed")
+	}))
+
+	if got := p.filter(FocusMsg{}); got != nil {
+		t.Errorf("filter returned %v after a panic, want nil", got)
+	}
+	if p.panicErr == nil || p.panicErr.Value != "filter failed" {
+		t.Errorf("panicErr = %v, want the filter panic", p.panicErr)
+	}
+	if p.ctx.Err() == nil {
+		t.Error("Program did not quit after the filter panicked")
+	}
+}
+
+// TestProgram_FilterSeesUpdatedModel tests that each queued message is
+// filtered against the model as the messages before it updated it.
+func TestProgram_FilterSeesUpdatedModel(t *testing.T) {
+	p := NewProgram(logModel{}, WithFilter(func(m Model, msg Msg) Msg {
+		// Drop focus once the model has received anything
+		if _, ok := msg.(FocusMsg); ok && len(m.(logModel).got) > 0 {
+			return nil
+		}
+		return msg
+	}))
+	p.cmdExec = NewCommandExecutor(p.ctx, p.msgChan)
+	defer p.cmdExec.Shutdown()
+
+	p.queue.post(p.msgChan, BlurMsg{})
+	p.queue.post(p.msgChan, FocusMsg{})
+	p.queue.post(p.msgChan, QuitMsg{})
+	p.Redraw(nil)
+
+	if got := p.model.(logModel).got; !reflect.DeepEqual(got, []Msg{BlurMsg{}}) {
+		t.Errorf("model received %v, want [BlurMsg{}]", got)
+	}
+}
@@ -0,0 +1,285 @@
+package lib
+
+import (
+	"math"
+	"time"
+)
+
+// MouseMode controls which mouse events the Program delivers to Update.
+type MouseMode int
+
+const (
+	// MouseModeNone disables mouse reporting. No MouseMsg values are sent.
+	MouseModeNone MouseMode = iota
+
+	// MouseModeCellMotion reports clicks, releases and wheel events, and
+	// motion events only while a mouse button is held (dragging).
+	MouseModeCellMotion
+
+	// MouseModeAllMotion reports clicks, releases, wheel events and every
+	// change of the cell under the pointer, whether or not a button is held.
+	MouseModeAllMotion
+)
+
+// String returns a string representation of the mouse mode for debugging.
+func (m MouseMode) String() string {
+	switch m {
+	case MouseModeNone:
+		return "MouseModeNone"
+	case MouseModeCellMotion:
+		return "MouseModeCellMotion"
+	case MouseModeAllMotion:
+		return "MouseModeAllMotion"
+	}
+	return "MouseMode(?)"
+}
+
+// reportsMotion reports whether a motion event should be delivered in this
+// mode, given whether a mouse button is currently held.
+func (m MouseMode) reportsMotion(buttonHeld bool) bool {
+	switch m {
+	case MouseModeAllMotion:
+		return true
+	case MouseModeCellMotion:
+		return buttonHeld
+	}
+	return false
+}
+
+// reportsButtons reports whether press, release and wheel events should be
+// delivered in this mode.
+func (m MouseMode) reportsButtons() bool {
+	return m != MouseModeNone
+}
+
+// clickTracker counts consecutive clicks of the same button that land
+// within a time interval and cell distance of the previous click.
+type clickTracker struct {
+	interval time.Duration
+	distance int
+
+	valid      bool
+	lastTime   uint32
+	lastX      int
+	lastY      int
+	lastButton MouseButton
+	count      int
+}
+
+// newClickTracker creates a clickTracker with the given multi-click interval
+// and maximum distance in cells between clicks.
+func newClickTracker(interval time.Duration, distance int) *clickTracker {
+	return &clickTracker{
+		interval: interval,
+		distance: distance,
+	}
+}
+
+// press records a button press at the given cell and event time in
+// milliseconds, and returns the resulting click count.
+func (c *clickTracker) press(x, y int, button MouseButton, ms uint32) int {
+	elapsed := time.Duration(ms-c.lastTime) * time.Millisecond
+	if c.valid &&
+		button == c.lastButton &&
+		elapsed <= c.interval &&
+		absInt(x-c.lastX) <= c.distance &&
+		absInt(y-c.lastY) <= c.distance {
+		c.count++
+	} else {
+		c.count = 1
+	}
+
+	c.valid = true
+	c.lastTime = ms
+	c.lastX = x
+	c.lastY = y
+	c.lastButton = button
+	return c.count
+}
+
+// release returns the click count of the press that the release of the
+// given button belongs to.
+func (c *clickTracker) release(button MouseButton) int {
+	if !c.valid || button != c.lastButton {
+		return 1
+	}
+	return c.count
+}
+
+// absInt returns the absolute value of n.
+func absInt(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// Pointer axis numbers as reported by the window system.
+const (
+	axisVertical   = 0
+	axisHorizontal = 1
+)
+
+// scrollAccumulator turns pointer axis events into whole-line scroll steps.
+// Discrete (wheel notch) counts are used as-is when present; otherwise axis
+// values are accumulated until they amount to at least one line, so that
+// touchpads do not flood Update and high-resolution wheels do not over-scroll.
+type scrollAccumulator struct {
+	pending     [2]float32
+	discrete    [2]int32
+	hasDiscrete [2]bool
+}
+
+// addDiscrete records a discrete step count for the next axis event on the
+// given axis. Discrete events precede the matching axis event.
+func (a *scrollAccumulator) addDiscrete(axis uint32, discrete int32) {
+	if axis > axisHorizontal {
+		return
+	}
+	a.discrete[axis] += discrete
+	a.hasDiscrete[axis] = true
+}
+
+// add accumulates an axis value and returns the number of whole steps it
+// completes. The sign of the result gives the scroll direction. unitsPerStep
+// is the axis distance that makes up one step.
+func (a *scrollAccumulator) add(axis uint32, value float32, unitsPerStep float32) int {
+	if axis > axisHorizontal {
+		return 0
+	}
+
+	if a.hasDiscrete[axis] {
+		steps := int(a.discrete[axis])
+		a.discrete[axis] = 0
+		a.hasDiscrete[axis] = false
+		a.pending[axis] = 0
+		return steps
+	}
+
+	if unitsPerStep <= 0 {
+		unitsPerStep = 1
+	}
+
+	// Reverse direction discards progress made in the other direction
+	if (value < 0) != (a.pending[axis] < 0) {
+		a.pending[axis] = 0
+	}
+
+	a.pending[axis] += value
+	steps := int(a.pending[axis] / unitsPerStep)
+	a.pending[axis] -= float32(steps) * unitsPerStep
+	return steps
+}
+
+// reset discards any partial step on the given axis.
+func (a *scrollAccumulator) reset(axis uint32) {
+	if axis > axisHorizontal {
+		return
+	}
+	a.pending[axis] = 0
+	a.discrete[axis] = 0
+	a.hasDiscrete[axis] = false
+}
+
+// Kinetic scrolling tuning.
+const (
+	// kineticDecay is the exponential velocity decay rate per second.
+	kineticDecay = 4.0
+
+	// kineticMinVelocity is the speed in axis units per second below which
+	// kinetic scrolling stops.
+	kineticMinVelocity = 20.0
+
+	// kineticMaxGap is the longest pause between finger scroll events that
+	// still counts towards the fling velocity.
+	kineticMaxGap = 100 * time.Millisecond
+)
+
+// kineticScroller continues finger scrolling after the fingers are lifted,
+// with a velocity that decays over time.
+type kineticScroller struct {
+	velocity  [2]float64
+	lastEvent [2]uint32
+	tracking  [2]bool
+	active    bool
+	lastStep  time.Time
+}
+
+// track updates the velocity estimate from a finger axis event with the
+// given event time in milliseconds. Any running fling is cancelled.
+func (k *kineticScroller) track(axis uint32, value float32, ms uint32) {
+	if axis > axisHorizontal {
+		return
+	}
+	k.active = false
+
+	gap := time.Duration(ms-k.lastEvent[axis]) * time.Millisecond
+	k.lastEvent[axis] = ms
+	if !k.tracking[axis] || gap > kineticMaxGap {
+		k.tracking[axis] = true
+		k.velocity[axis] = 0
+		return
+	}
+
+	if gap < time.Millisecond {
+		gap = time.Millisecond
+	}
+	instant := float64(value) / gap.Seconds()
+	k.velocity[axis] = 0.7*instant + 0.3*k.velocity[axis]
+}
+
+// fling starts kinetic scrolling from the tracked velocity when the fingers
+// leave the touch surface. It reports whether scrolling was started.
+func (k *kineticScroller) fling(axis uint32, now time.Time) bool {
+	if axis > axisHorizontal {
+		return false
+	}
+	k.tracking[axis] = false
+	if math.Abs(k.velocity[axis]) < kineticMinVelocity {
+		k.velocity[axis] = 0
+		return k.active
+	}
+	k.active = true
+	k.lastStep = now
+	return true
+}
+
+// step advances kinetic scrolling to the given time and returns the axis
+// distance travelled on each axis since the previous step.
+func (k *kineticScroller) step(now time.Time) [2]float32 {
+	var deltas [2]float32
+	if !k.active {
+		return deltas
+	}
+
+	dt := now.Sub(k.lastStep).Seconds()
+	k.lastStep = now
+	if dt <= 0 {
+		return deltas
+	}
+
+	decay := math.Exp(-kineticDecay * dt)
+	moving := false
+	for axis := range k.velocity {
+		v := k.velocity[axis]
+		if v == 0 {
+			continue
+		}
+		// Distance is the integral of v*e^(-decay*t) over the step
+		deltas[axis] = float32(v * (1 - decay) / kineticDecay)
+		k.velocity[axis] = v * decay
+		if math.Abs(k.velocity[axis]) < kineticMinVelocity {
+			k.velocity[axis] = 0
+		} else {
+			moving = true
+		}
+	}
+	k.active = moving
+	return deltas
+}
+
+// stop cancels kinetic scrolling.
+func (k *kineticScroller) stop() {
+	k.active = false
+	k.velocity = [2]float64{}
+}
@@ -0,0 +1,266 @@
+package lib
+
+import (
+	"testing"
+	"time"
+
+	"github.com/neurlang/wayland/window"
+)
+
+func TestMouseMode_Reporting(t *testing.T) {
+	tests := []struct {
+		mode        MouseMode
+		held        bool
+		wantMotion  bool
+		wantButtons bool
+	}{
+		{MouseModeNone, false, false, false},
+		{MouseModeNone, true, false, false},
+		{MouseModeCellMotion, false, false, true},
+		{MouseModeCellMotion, true, true, true},
+		{MouseModeAllMotion, false, true, true},
+		{MouseModeAllMotion, true, true, true},
+	}
+
+	for _, tt := range tests {
+		if got := tt.mode.reportsMotion(tt.held); got != tt.wantMotion {
+			t.Errorf("%v.reportsMotion(%v) = %v, want %v", tt.mode, tt.held, got, tt.wantMotion)
+		}
+		if got := tt.mode.reportsButtons(); got != tt.wantButtons {
+			t.Errorf("%v.reportsButtons() = %v, want %v", tt.mode, got, tt.wantButtons)
+		}
+	}
+}
+
+func TestMouseModeCommands(t *testing.T) {
+	tests := []struct {
+		name string
+		cmd  Cmd
+		want MouseMode
+	}{
+		{"EnableMouseCellMotion", EnableMouseCellMotion, MouseModeCellMotion},
+		{"EnableMouseAllMotion", EnableMouseAllMotion, MouseModeAllMotion},
+		{"DisableMouse", DisableMouse, MouseModeNone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, ok := tt.cmd().(setMouseModeMsg)
+			if !ok {
+				t.Fatalf("%s returned %T, want setMouseModeMsg", tt.name, tt.cmd())
+			}
+			if msg.mode != tt.want {
+				t.Errorf("%s set mode %v, want %v", tt.name, msg.mode, tt.want)
+			}
+		})
+	}
+}
+
+func TestMouseOptions(t *testing.T) {
+	p := NewProgram(nil)
+	if p.mouseMode != MouseModeAllMotion {
+		t.Errorf("default mouse mode = %v, want %v", p.mouseMode, MouseModeAllMotion)
+	}
+
+	p = NewProgram(nil, WithMouseCellMotion())
+	if p.mouseMode != MouseModeCellMotion {
+		t.Errorf("WithMouseCellMotion mode = %v, want %v", p.mouseMode, MouseModeCellMotion)
+	}
+
+	p = NewProgram(nil, WithoutMouse())
+	if p.mouseMode != MouseModeNone {
+		t.Errorf("WithoutMouse mode = %v, want %v", p.mouseMode, MouseModeNone)
+	}
+}
+
+func TestClickTracker_CountsConsecutiveClicks(t *testing.T) {
+	c := newClickTracker(500*time.Millisecond, 1)
+
+	if got := c.press(5, 5, MouseButtonLeft, 1000); got != 1 {
+		t.Errorf("first press count = %d, want 1", got)
+	}
+	if got := c.release(MouseButtonLeft); got != 1 {
+		t.Errorf("first release count = %d, want 1", got)
+	}
+	if got := c.press(5, 5, MouseButtonLeft, 1200); got != 2 {
+		t.Errorf("second press count = %d, want 2", got)
+	}
+	if got := c.release(MouseButtonLeft); got != 2 {
+		t.Errorf("second release count = %d, want 2", got)
+	}
+	if got := c.press(6, 4, MouseButtonLeft, 1400); got != 3 {
+		t.Errorf("third press within distance count = %d, want 3", got)
+	}
+}
+
+func TestClickTracker_ResetsSeries(t *testing.T) {
+	tests := []struct {
+		name   string
+		x, y   int
+		button MouseButton
+		ms     uint32
+	}{
+		{"too slow", 5, 5, MouseButtonLeft, 1600},
+		{"too far", 7, 5, MouseButtonLeft, 1100},
+		{"other button", 5, 5, MouseButtonRight, 1100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newClickTracker(500*time.Millisecond, 1)
+			c.press(5, 5, MouseButtonLeft, 1000)
+			if got := c.press(tt.x, tt.y, tt.button, tt.ms); got != 1 {
+				t.Errorf("press count = %d, want 1", got)
+			}
+		})
+	}
+}
+
+func TestClickTracker_TimestampWraparound(t *testing.T) {
+	c := newClickTracker(500*time.Millisecond, 1)
+	c.press(0, 0, MouseButtonLeft, ^uint32(0)-100)
+	if got := c.press(0, 0, MouseButtonLeft, 100); got != 2 {
+		t.Errorf("press count across timestamp wraparound = %d, want 2", got)
+	}
+}
+
+func TestScrollAccumulator_DiscreteSteps(t *testing.T) {
+	var a scrollAccumulator
+
+	a.addDiscrete(axisVertical, 2)
+	if got := a.add(axisVertical, 30, 15); got != 2 {
+		t.Errorf("discrete steps = %d, want 2", got)
+	}
+
+	// Without a discrete event the value is accumulated
+	if got := a.add(axisVertical, 5, 15); got != 0 {
+		t.Errorf("partial step = %d, want 0", got)
+	}
+}
+
+func TestScrollAccumulator_AccumulatesSmallValues(t *testing.T) {
+	var a scrollAccumulator
+
+	total := 0
+	for i := 0; i < 10; i++ {
+		total += a.add(axisVertical, 4, 10)
+	}
+	if total != 4 {
+		t.Errorf("accumulated steps = %d, want 4", total)
+	}
+
+	if got := a.add(axisVertical, -25, 10); got != -2 {
+		t.Errorf("reverse steps = %d, want -2", got)
+	}
+
+	a.add(axisHorizontal, 9, 10)
+	a.reset(axisHorizontal)
+	if got := a.add(axisHorizontal, 9, 10); got != 0 {
+		t.Errorf("steps after reset = %d, want 0", got)
+	}
+}
+
+func TestKineticScroller_FlingDecays(t *testing.T) {
+	var k kineticScroller
+
+	for i := uint32(0); i < 5; i++ {
+		k.track(axisVertical, 20, 1000+i*10)
+	}
+	start := time.Now()
+	if !k.fling(axisVertical, start) {
+		t.Fatal("fling did not start")
+	}
+
+	first := k.step(start.Add(16 * time.Millisecond))
+	if first[axisVertical] <= 0 {
+		t.Fatalf("first kinetic delta = %v, want positive", first[axisVertical])
+	}
+	second := k.step(start.Add(32 * time.Millisecond))
+	if second[axisVertical] >= first[axisVertical] {
+		t.Errorf("kinetic delta did not decay: %v then %v", first[axisVertical], second[axisVertical])
+	}
+
+	k.step(start.Add(10 * time.Second))
+	if k.active {
+		t.Error("kinetic scrolling still active after 10 seconds")
+	}
+}
+
+func TestKineticScroller_SlowFingersDoNotFling(t *testing.T) {
+	var k kineticScroller
+
+	k.track(axisVertical, 1, 1000)
+	k.track(axisVertical, 1, 1090)
+	if k.fling(axisVertical, time.Now()) {
+		t.Error("fling started for slow scrolling")
+	}
+}
+
+func TestFocusReporting(t *testing.T) {
+	p := NewProgram(nil, WithReportFocus())
+	p.msgChan = make(chan Msg, 8)
+
+	p.Focus(nil, &window.Input{})
+	p.Focus(nil, &window.Input{})
+	p.Focus(nil, nil)
+
+	want := []Msg{
+		WindowStateMsg{Activated: true},
+		FocusMsg{},
+		WindowStateMsg{Activated: false},
+		BlurMsg{},
+	}
+	for i, w := range want {
+		if msg := <-p.msgChan; msg != w {
+			t.Errorf("message %d = %v, want %v", i, msg, w)
+		}
+	}
+	if len(p.msgChan) != 0 {
+		t.Errorf("unexpected extra messages: %d", len(p.msgChan))
+	}
+
+	// Without the option no focus messages are sent
+	p = NewProgram(nil)
+	p.msgChan = make(chan Msg, 8)
+	p.Focus(nil, &window.Input{})
+	for len(p.msgChan) > 0 {
+		switch msg := <-p.msgChan; msg.(type) {
+		case FocusMsg, BlurMsg:
+			t.Errorf("focus reported without WithReportFocus: %v", msg)
+		}
+	}
+}
+
+func TestMouseEnterLeave(t *testing.T) {
+	p := NewProgram(nil)
+	p.msgChan = make(chan Msg, 4)
+	renderer, err := NewRenderer(RendererOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create renderer: %v", err)
+	}
+	p.renderer = renderer
+	cw, ch := float32(renderer.CellWidth()), float32(renderer.CellHeight())
+
+	p.pointerEntered(3.5*cw, 2.5*ch)
+	p.pointerEntered(4.5*cw, 2.5*ch)
+	p.Leave(nil, nil)
+	p.Leave(nil, nil)
+
+	if msg := <-p.msgChan; msg != (MouseEnterMsg{X: 3, Y: 2}) {
+		t.Errorf("first message = %v, want MouseEnterMsg{X: 3, Y: 2}", msg)
+	}
+	if msg := <-p.msgChan; msg != (MouseLeaveMsg{}) {
+		t.Errorf("second message = %v, want MouseLeaveMsg", msg)
+	}
+	if len(p.msgChan) != 0 {
+		t.Errorf("unexpected extra messages: %d", len(p.msgChan))
+	}
+
+	p = NewProgram(nil, WithoutMouse())
+	p.msgChan = make(chan Msg, 4)
+	p.pointerEntered(0, 0)
+	p.Leave(nil, nil)
+	if len(p.msgChan) != 0 {
+		t.Errorf("enter/leave reported with mouse disabled")
+	}
+}
@@ -0,0 +1,195 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// WindowID identifies a window of the application. Secondary windows get
+// their ID from OpenWindow.
+type WindowID int
+
+// MainWindow is the ID of the window created by Program.Run.
+const MainWindow WindowID = 0
+
+// errSecondaryWindows is reported when the window backend cannot open
+// secondary windows.
+var errSecondaryWindows = errors.New("secondary windows are not supported by this window backend")
+
+// lastWindowID is the ID most recently given to a secondary window.
+var lastWindowID atomic.Int64
+
+// openWindowMsg is an internal message that opens a secondary window.
+type openWindowMsg struct {
+	id    WindowID
+	model Model
+	opts  []ProgramOption
+}
+
+// closeWindowMsg is an internal message, handled by the main program, that
+// closes a secondary window.
+type closeWindowMsg struct {
+	window *Program
+}
+
+// OpenWindow returns the ID of a new secondary window and a command that
+// opens it. The window runs model with its own Init, Update and View, and
+// shares the display connection of the program. Options configure it like
+// the options of NewProgram.
+//
+// When the window closes, because its model returned Quit or the user
+// closed it, the model that opened it receives a WindowClosedMsg. Closing
+// the main window closes all secondary windows.
+func OpenWindow(model Model, opts ...ProgramOption) (WindowID, Cmd) {
+	id := WindowID(lastWindowID.Add(1))
+	return id, func() Msg {
+		return openWindowMsg{id: id, model: model, opts: opts}
+	}
+}
+
+// SendTo sends a message to the Update function of the window with the
+// given ID. Use MainWindow for the window created by Run. It reports
+// whether the window is open. This is thread-safe and can be called from
+// any goroutine.
+func (p *Program) SendTo(id WindowID, msg Msg) bool {
+	target := p.lookupWindow(id)
+	if target == nil {
+		return false
+	}
+	target.Send(msg)
+	return true
+}
+
+// mainProgram returns the program that owns the main window.
+func (p *Program) mainProgram() *Program {
+	if p.main != nil {
+		return p.main
+	}
+	return p
+}
+
+// lookupWindow returns the program of the window with the given ID, or nil
+// if it is not open.
+func (p *Program) lookupWindow(id WindowID) *Program {
+	main := p.mainProgram()
+	if id == MainWindow {
+		return main
+	}
+
+	main.windowsMu.Lock()
+	defer main.windowsMu.Unlock()
+	return main.windows[id]
+}
+
+// openWindow creates a secondary window on the display of p and starts its
+// model. If the window cannot be created, WindowClosedMsg is sent with the
+// error. Must be called from the display thread with p.mu held.
+func (p *Program) openWindow(m openWindowMsg) {
+	child := NewProgram(m.model, m.opts...)
+	child.id = m.id
+	child.main = p.mainProgram()
+	child.opener = p
+	child.display = p.display
+
+	err := child.validateOptions()
+	if err != nil {
+		err = fmt.Errorf("invalid configuration: %w", err)
+	} else if !secondaryWindows {
+		err = errSecondaryWindows
+	} else if p.display == nil {
+		err = errors.New("no display connection, embedded programs cannot open windows")
+	} else if err = child.createWindow(); err == nil {
+		err = child.watchClose()
+	}
+	if err != nil {
+		Warn("Failed to open window %d: %v", m.id, err)
+		child.cancel()
+		child.destroyWindow()
+		p.notifyClosed(WindowClosedMsg{ID: m.id, Err: err})
+		return
+	}
+
+	main := child.main
+	main.windowsMu.Lock()
+	if main.windows == nil {
+		main.windows = make(map[WindowID]*Program)
+	}
+	main.windows[m.id] = child
+	main.windowsMu.Unlock()
+
+	Debug("Opened window %d", m.id)
+	child.startModel()
+}
+
+// requestClose asks the main program to close this secondary window. The
+// window is destroyed from the main program's redraw, outside of the
+// window's own callbacks.
+func (p *Program) requestClose() {
+	p.cancel()
+	main := p.mainProgram()
+	go main.Send(closeWindowMsg{window: p})
+}
+
+// closeWindow destroys a secondary window and the windows it opened, and
+// sends WindowClosedMsg to the model that opened it. Closing a window that
+// is already closed does nothing. Must be called from the display thread.
+func (p *Program) closeWindow(child *Program) {
+	p.windowsMu.Lock()
+	if p.windows[child.id] != child {
+		p.windowsMu.Unlock()
+		return
+	}
+	delete(p.windows, child.id)
+	var opened []*Program
+	for _, w := range p.windows {
+		if w.opener == child {
+			opened = append(opened, w)
+		}
+	}
+	p.windowsMu.Unlock()
+
+	for _, w := range opened {
+		p.closeWindow(w)
+	}
+
+	child.mu.Lock()
+	child.cancel()
+	child.destroyWindow()
+	child.mu.Unlock()
+
+	// Commands may still be running; don't block the display thread
+	go child.shutdownCommands()
+	child.markDone()
+
+	Debug("Closed window %d", child.id)
+	closed := WindowClosedMsg{ID: child.id}
+	if child.panicErr != nil {
+		closed.Err = child.panicErr
+	}
+	child.opener.notifyClosed(closed)
+}
+
+// closeWindows closes all secondary windows. It is called when the main
+// program exits.
+func (p *Program) closeWindows() {
+	p.windowsMu.Lock()
+	var all []*Program
+	for _, w := range p.windows {
+		all = append(all, w)
+	}
+	p.windowsMu.Unlock()
+
+	for _, w := range all {
+		p.closeWindow(w)
+	}
+}
+
+// notifyClosed delivers a WindowClosedMsg to the model of p.
+func (p *Program) notifyClosed(msg WindowClosedMsg) {
+	// Sent without blocking because the caller may hold p.mu while the
+	// channel is only drained in Redraw
+	if p.queue.post(p.msgChan, msg) {
+		p.scheduleRedraw()
+	}
+}
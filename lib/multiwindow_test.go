@@ -0,0 +1,141 @@
+package lib
+
+import (
+	"reflect"
+	"testing"
+)
+
+// newTestChild registers a secondary window program without creating a
+// real window.
+func newTestChild(main, opener *Program, id WindowID) *Program {
+	child := NewProgram(nil)
+	child.id = id
+	child.main = main
+	child.opener = opener
+	child.cmdExec = NewCommandExecutor(child.ctx, child.msgChan)
+
+	main.windowsMu.Lock()
+	if main.windows == nil {
+		main.windows = make(map[WindowID]*Program)
+	}
+	main.windows[id] = child
+	main.windowsMu.Unlock()
+	return child
+}
+
+func TestOpenWindow(t *testing.T) {
+	id1, cmd := OpenWindow(nil, WithWindowTitle("Inspector"))
+	id2, _ := OpenWindow(nil)
+
+	if id1 == MainWindow || id2 == MainWindow || id1 == id2 {
+		t.Fatalf("window IDs %d and %d are not unique", id1, id2)
+	}
+
+	msg, ok := cmd().(openWindowMsg)
+	if !ok {
+		t.Fatalf("OpenWindow command returned %T", cmd())
+	}
+	if msg.id != id1 || len(msg.opts) != 1 {
+		t.Errorf("open message = %+v", msg)
+	}
+}
+
+func TestOpenWindow_InvalidOptions(t *testing.T) {
+	p := NewProgram(nil)
+	id, cmd := OpenWindow(nil, WithFontSize(0))
+
+	if !p.handleProgramMsg(cmd()) {
+		t.Fatal("open window message reached Update")
+	}
+
+	msg := (<-p.msgChan).(WindowClosedMsg)
+	if msg.ID != id || msg.Err == nil {
+		t.Errorf("closed message = %v, want an error for window %d", msg, id)
+	}
+	if p.lookupWindow(id) != nil {
+		t.Error("window that failed to open was registered")
+	}
+}
+
+func TestSendTo(t *testing.T) {
+	p := NewProgram(nil)
+	child := newTestChild(p, p, 42)
+
+	if !child.SendTo(MainWindow, FocusMsg{}) {
+		t.Error("SendTo(MainWindow) reported the main window as closed")
+	}
+	if msg := <-p.msgChan; msg != (FocusMsg{}) {
+		t.Errorf("main window received %v", msg)
+	}
+
+	if !p.SendTo(42, BlurMsg{}) {
+		t.Error("SendTo(42) reported an open window as closed")
+	}
+	if msg := <-child.msgChan; msg != (BlurMsg{}) {
+		t.Errorf("secondary window received %v", msg)
+	}
+
+	if p.SendTo(7, FocusMsg{}) {
+		t.Error("SendTo reported an unknown window as open")
+	}
+}
+
+func TestCloseWindow(t *testing.T) {
+	p := NewProgram(nil)
+	child := newTestChild(p, p, 10)
+	grandchild := newTestChild(p, child, 11)
+	other := newTestChild(p, p, 12)
+
+	// Quitting a secondary window asks the main program to close it
+	child.quit()
+	if p.ctx.Err() != nil {
+		t.Fatal("quitting a secondary window cancelled the main program")
+	}
+	if msg := <-p.msgChan; msg != (closeWindowMsg{window: child}) {
+		t.Fatalf("main program received %v, want a close request", msg)
+	}
+	if !p.handleProgramMsg(closeWindowMsg{window: child}) {
+		t.Fatal("close request reached Update")
+	}
+
+	// Windows opened by the closed window are closed with it
+	if p.lookupWindow(10) != nil || p.lookupWindow(11) != nil {
+		t.Error("closed windows are still registered")
+	}
+	if p.lookupWindow(12) != other {
+		t.Error("unrelated window was closed")
+	}
+	if child.ctx.Err() == nil || grandchild.ctx.Err() == nil {
+		t.Error("closed windows were not cancelled")
+	}
+	if msg := <-p.msgChan; msg != (WindowClosedMsg{ID: 10}) {
+		t.Errorf("opener received %v, want WindowClosedMsg for window 10", msg)
+	}
+
+	// Closing twice does nothing
+	p.closeWindow(child)
+	if len(p.msgChan) != 0 {
+		t.Error("closing a closed window sent another message")
+	}
+
+	// The main program closes the rest when it exits
+	p.closeWindows()
+	if p.lookupWindow(12) != nil {
+		t.Error("window still open after closeWindows")
+	}
+}
+
+// TestCloseWindow_FullQueue tests that WindowClosedMsg reaches the opener
+// even when its queue is full.
+func TestCloseWindow_FullQueue(t *testing.T) {
+	p := NewProgram(nil, WithQueueSize(1))
+	child := newTestChild(p, p, 10)
+	p.Send(FocusMsg{})
+
+	p.closeWindow(child)
+
+	want := []Msg{FocusMsg{}, WindowClosedMsg{ID: 10}}
+	if got := p.queue.drain(p.msgChan); !reflect.DeepEqual(got, want) {
+		t.Errorf("opener received %v, want %v", got, want)
+	}
+}
@@ -22,6 +22,11 @@ func ParseANSI(output string, width, height int) *TerminalGrid {
 	}
 
 	parser.parse(output)
+
+	// Zones left open at the end of the view extend to its last cell
+	for len(parser.zones) > 0 {
+		parser.closeZone()
+	}
 	return grid
 }
 
@@ -36,6 +41,13 @@ type ansiParser struct {
 	italic        bool
 	underline     bool
 	strikethrough bool
+	zones         []openZone
+
+	// wrapPending is set when a character was printed in the last column.
+	// Like a terminal, the cursor only moves to the next line when
+	// another character is printed, so a line that fills the width is not
+	// followed by an empty one.
+	wrapPending bool
 }
 
 // parse processes the input string and populates the grid.
@@ -62,12 +74,19 @@ func (p *ansiParser) parse(input string) {
 		case '\n':
 			p.cursorX = 0
 			p.cursorY++
+			p.wrapPending = false
 		case '\r':
 			p.cursorX = 0
+			p.wrapPending = false
 		case '\t':
 			// Tab moves to next multiple of 8
 			p.cursorX = ((p.cursorX / 8) + 1) * 8
 		default:
+			if p.wrapPending {
+				p.cursorX = 0
+				p.cursorY++
+				p.wrapPending = false
+			}
 			if p.cursorY < p.grid.Height && p.cursorX < p.grid.Width {
 				p.grid.Cells[p.cursorY][p.cursorX] = Cell{
 					Rune:          ch,
@@ -78,14 +97,17 @@ func (p *ansiParser) parse(input string) {
 					Underline:     p.underline,
 					Strikethrough: p.strikethrough,
 				}
+				if len(p.zones) > 0 {
+					p.markZoneCell(p.cursorX, p.cursorY)
+				}
 			}
 			p.cursorX++
 		}
 
 		// Handle line wrapping
 		if p.cursorX >= p.grid.Width {
-			p.cursorX = 0
-			p.cursorY++
+			p.cursorX = p.grid.Width - 1
+			p.wrapPending = true
 		}
 
 		i++
@@ -116,6 +138,12 @@ func (p *ansiParser) handleEscapeSequence(seq string) {
 		params = seq[:len(seq)-1]
 	}
 
+	// Moving the cursor cancels a pending wrap
+	switch command {
+	case 'H', 'f', 'A', 'B', 'C', 'D':
+		p.wrapPending = false
+	}
+
 	switch command {
 	case 'm': // SGR - Select Graphic Rendition
 		p.handleSGR(params)
@@ -133,6 +161,8 @@ func (p *ansiParser) handleEscapeSequence(seq string) {
 		p.handleEraseDisplay(params)
 	case 'K': // Erase in line
 		p.handleEraseLine(params)
+	case zoneFinal: // Zone marker (see Mark)
+		p.handleZoneMarker(params)
 	}
 }
 
@@ -344,7 +374,8 @@ func (p *ansiParser) handleCursorDown(params string) {
 	p.cursorY += n
 }
 
-// handleCursorForward moves the cursor forward by n columns.
+// handleCursorForward moves the cursor forward by n columns, stopping at
+// the last column like a terminal.
 func (p *ansiParser) handleCursorForward(params string) {
 	n := 1
 	if params != "" {
@@ -352,7 +383,7 @@ func (p *ansiParser) handleCursorForward(params string) {
 			n = num
 		}
 	}
-	p.cursorX += n
+	p.cursorX = min(p.cursorX+n, p.grid.Width-1)
 }
 
 // handleCursorBack moves the cursor back by n columns.
@@ -1,6 +1,7 @@
 package lib
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -171,3 +172,70 @@ func TestTerminalGrid_Diff(t *testing.T) {
 		t.Error("Expected differences after changing a cell")
 	}
 }
+
+// TestParseANSI_FullLineWrap tests that a line filling the width wraps only
+// when another character follows, like in a terminal.
+func TestParseANSI_FullLineWrap(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"abc\nde\nfgh", []string{"abc", "de ", "fgh"}},
+		{"abcdef", []string{"abc", "def", "   "}},
+		{"abc\r\nd", []string{"abc", "d  ", "   "}},
+		{"abc\x1b[1;1Hx", []string{"xbc", "   ", "   "}},
+	}
+
+	for _, tt := range tests {
+		grid := ParseANSI(tt.input, 3, 3)
+		for y, want := range tt.want {
+			var row strings.Builder
+			for x := 0; x < grid.Width; x++ {
+				row.WriteRune(grid.Cells[y][x].Rune)
+			}
+			if got := row.String(); got != want {
+				t.Errorf("ParseANSI(%q) row %d = %q, want %q", tt.input, y, got, want)
+			}
+		}
+	}
+}
+
+// TestParseANSI_ExactFill tests text that exactly fills the last column: the
+// cursor stays in that column until another character is printed, newlines
+// and carriage returns do not add an empty line, styles keep the pending
+// wrap, and cursor movement cancels it.
+func TestParseANSI_ExactFill(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{"end of text", "abc", []string{"abc", "   ", "   "}},
+		{"newline", "abc\nd", []string{"abc", "d  ", "   "}},
+		{"two newlines", "abc\n\nd", []string{"abc", "   ", "d  "}},
+		{"carriage return", "abc\rd", []string{"dbc", "   ", "   "}},
+		{"more text", "abcd", []string{"abc", "d  ", "   "}},
+		{"styled", "ab\x1b[1mc\x1b[0md", []string{"abc", "d  ", "   "}},
+		{"cursor back", "abc\x1b[Dd", []string{"adc", "   ", "   "}},
+		{"cursor forward", "abc\x1b[Cd", []string{"abd", "   ", "   "}},
+		{"cursor down", "abc\x1b[Bd", []string{"abc", "  d", "   "}},
+		{"tab", "abc\td", []string{"abc", "d  ", "   "}},
+		{"last row", "abcdefghi", []string{"abc", "def", "ghi"}},
+		{"past last row", "abcdefghijk", []string{"abc", "def", "ghi"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			grid := ParseANSI(tt.input, 3, 3)
+			for y, want := range tt.want {
+				var row strings.Builder
+				for x := 0; x < grid.Width; x++ {
+					row.WriteRune(grid.Cells[y][x].Rune)
+				}
+				if got := row.String(); got != want {
+					t.Errorf("ParseANSI(%q) row %d = %q, want %q", tt.input, y, got, want)
+				}
+			}
+		})
+	}
+}
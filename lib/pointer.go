@@ -0,0 +1,111 @@
+package lib
+
+// PointerShape is the shape of the mouse pointer over the window.
+type PointerShape int
+
+const (
+	// PointerDefault is the normal arrow pointer.
+	PointerDefault PointerShape = iota
+
+	// PointerText is the I-beam shown over editable or selectable text.
+	PointerText
+
+	// PointerHand is the pointing hand shown over links and buttons.
+	PointerHand
+
+	// PointerCrosshair is a crosshair for precise selection.
+	PointerCrosshair
+
+	// PointerWait indicates that the application is busy.
+	PointerWait
+
+	// PointerHidden hides the pointer.
+	PointerHidden
+
+	// PointerGrabbing is a closed hand shown while dragging content.
+	PointerGrabbing
+
+	// PointerNotAllowed indicates that an action is not possible.
+	PointerNotAllowed
+
+	// Resize arrows for window edges and corners or resizable panes.
+	PointerResizeN
+	PointerResizeS
+	PointerResizeE
+	PointerResizeW
+	PointerResizeNE
+	PointerResizeNW
+	PointerResizeSE
+	PointerResizeSW
+)
+
+// String returns a string representation of the pointer shape for debugging.
+func (s PointerShape) String() string {
+	switch s {
+	case PointerDefault:
+		return "PointerDefault"
+	case PointerText:
+		return "PointerText"
+	case PointerHand:
+		return "PointerHand"
+	case PointerCrosshair:
+		return "PointerCrosshair"
+	case PointerWait:
+		return "PointerWait"
+	case PointerHidden:
+		return "PointerHidden"
+	case PointerGrabbing:
+		return "PointerGrabbing"
+	case PointerNotAllowed:
+		return "PointerNotAllowed"
+	case PointerResizeN:
+		return "PointerResizeN"
+	case PointerResizeS:
+		return "PointerResizeS"
+	case PointerResizeE:
+		return "PointerResizeE"
+	case PointerResizeW:
+		return "PointerResizeW"
+	case PointerResizeNE:
+		return "PointerResizeNE"
+	case PointerResizeNW:
+		return "PointerResizeNW"
+	case PointerResizeSE:
+		return "PointerResizeSE"
+	case PointerResizeSW:
+		return "PointerResizeSW"
+	}
+	return "PointerShape(?)"
+}
+
+// setPointerShapeMsg is an internal message that changes the pointer shape.
+type setPointerShapeMsg struct {
+	shape PointerShape
+}
+
+// SetPointerShape returns a command that changes the pointer shape shown over
+// the whole window. Zones marked with MarkWithPointer show their own shape
+// only while the shape is PointerDefault, so setting it back to
+// PointerDefault restores them. On Wayland the new shape appears right away
+// when the pointer is over the window. On Windows, and in embedded programs,
+// whose host sets the pointer, it appears when the pointer next moves.
+func SetPointerShape(shape PointerShape) Cmd {
+	return func() Msg {
+		return setPointerShapeMsg{shape: shape}
+	}
+}
+
+// pointerShapeAt returns the pointer shape to show over the given cell.
+// Must be called with p.mu held.
+func (p *Program) pointerShapeAt(x, y int) PointerShape {
+	if p.pointerShape != PointerDefault {
+		return p.pointerShape
+	}
+
+	p.gridMu.Lock()
+	defer p.gridMu.Unlock()
+	if p.grid == nil {
+		return PointerDefault
+	}
+	return p.grid.pointerAt(x, y)
+}
@@ -0,0 +1,326 @@
+package lib
+
+import (
+	"github.com/neurlang/wayland/window"
+	"github.com/neurlang/wayland/wl"
+	"github.com/neurlang/wayland/wlcursor"
+)
+
+// cursor returns the backend cursor for the pointer shape, which the
+// backend shows where the program cannot set the pointer itself. The
+// crosshair is not one of the backend cursors; the default arrow stands in.
+func (s PointerShape) cursor() int {
+	switch s {
+	case PointerText:
+		return window.CursorIbeam
+	case PointerHand:
+		return window.CursorHand1
+	case PointerWait:
+		return window.CursorWatch
+	case PointerHidden:
+		return window.CursorBlank
+	case PointerGrabbing:
+		return window.CursorDragging
+	case PointerNotAllowed:
+		return window.CursorDndForbidden
+	case PointerResizeN:
+		return window.CursorTop
+	case PointerResizeS:
+		return window.CursorBottom
+	case PointerResizeE:
+		return window.CursorRight
+	case PointerResizeW:
+		return window.CursorLeft
+	case PointerResizeNE:
+		return window.CursorTopRight
+	case PointerResizeNW:
+		return window.CursorTopLeft
+	case PointerResizeSE:
+		return window.CursorBottomRight
+	case PointerResizeSW:
+		return window.CursorBottomLeft
+	}
+	return window.CursorLeftPtr
+}
+
+// cursorShape returns the cursor-shape-v1 shape for the pointer shape.
+func (s PointerShape) cursorShape() uint32 {
+	switch s {
+	case PointerText:
+		return 9
+	case PointerHand:
+		return 4
+	case PointerCrosshair:
+		return 8
+	case PointerWait:
+		return 6
+	case PointerGrabbing:
+		return 17
+	case PointerNotAllowed:
+		return 15
+	case PointerResizeN:
+		return 19
+	case PointerResizeS:
+		return 22
+	case PointerResizeE:
+		return 18
+	case PointerResizeW:
+		return 25
+	case PointerResizeNE:
+		return 20
+	case PointerResizeNW:
+		return 21
+	case PointerResizeSE:
+		return 23
+	case PointerResizeSW:
+		return 24
+	}
+	return 1
+}
+
+// themeCursors returns the names of the pointer shape in cursor themes,
+// preferred first.
+func (s PointerShape) themeCursors() []string {
+	switch s {
+	case PointerText:
+		return []string{"xterm", "text"}
+	case PointerHand:
+		return []string{"hand2", "pointer", "hand1"}
+	case PointerCrosshair:
+		return []string{"crosshair", "cross", "tcross"}
+	case PointerWait:
+		return []string{"watch", "wait"}
+	case PointerGrabbing:
+		return []string{"grabbing", "closedhand", "fleur"}
+	case PointerNotAllowed:
+		return []string{"not-allowed", "crossed_circle"}
+	case PointerResizeN:
+		return []string{"top_side", "n-resize"}
+	case PointerResizeS:
+		return []string{"bottom_side", "s-resize"}
+	case PointerResizeE:
+		return []string{"right_side", "e-resize"}
+	case PointerResizeW:
+		return []string{"left_side", "w-resize"}
+	case PointerResizeNE:
+		return []string{"top_right_corner", "ne-resize"}
+	case PointerResizeNW:
+		return []string{"top_left_corner", "nw-resize"}
+	case PointerResizeSE:
+		return []string{"bottom_right_corner", "se-resize"}
+	case PointerResizeSW:
+		return []string{"bottom_left_corner", "sw-resize"}
+	}
+	return []string{"left_ptr", "default"}
+}
+
+// pointerCursor shows the pointer shape over the window and returns the
+// cursor the backend should show. The program sets the pointer of its own
+// seat and tells the backend to leave the pointer alone, unless it has no
+// pointer over the window. The host of an embedded program owns the pointer,
+// so embedded programs always return the backend cursor. moved is set for
+// pointer motion. Must be called on the display thread with p.mu held.
+func (p *Program) pointerCursor(shape PointerShape, moved bool) int {
+	if p.embedded {
+		return shape.cursor()
+	}
+	dropTargets.Lock()
+	dev := dropTargets.devices[p.display]
+	dropTargets.Unlock()
+	if dev == nil || !dev.showPointer(p.window, shape, moved) {
+		return shape.cursor()
+	}
+	return window.CursorUnset
+}
+
+// showPointerShape shows the pointer shape over the window right away,
+// instead of when the pointer next moves. Must be called on the display
+// thread with p.mu held.
+func (p *Program) showPointerShape() {
+	if p.renderer == nil || p.selection.dragging {
+		return
+	}
+	cellX, cellY := p.cellAt(p.pointerX, p.pointerY)
+	p.pointerCursor(p.pointerShapeAt(cellX, cellY), false)
+}
+
+// HandleSeatCapabilities creates the pointer of the seat, or releases it
+// when the seat loses its pointer.
+func (d *dropDevice) HandleSeatCapabilities(ev wl.SeatCapabilitiesEvent) {
+	hasPointer := ev.Capabilities&wl.SeatCapabilityPointer != 0
+	if hasPointer && d.pointer == nil {
+		pointer, err := d.seat.GetPointer()
+		if err != nil {
+			Warn("Failed to get the pointer of the seat: %v", err)
+			return
+		}
+		pointer.AddEnterHandler(d)
+		pointer.AddLeaveHandler(d)
+		d.pointer = pointer
+		d.createCursorShapeDevice()
+	} else if !hasPointer && d.pointer != nil {
+		d.destroyPointer()
+	}
+}
+
+// createCursorShapeDevice creates the cursor shape device of the pointer
+// once the compositor announced the cursor shape manager.
+func (d *dropDevice) createCursorShapeDevice() {
+	if d.pointer == nil || d.cursorShapeManager == nil || d.cursorShape != nil {
+		return
+	}
+	dev, err := d.cursorShapeManager.getPointer(d.pointer)
+	if err != nil {
+		Warn("Failed to create the cursor shape device: %v", err)
+		return
+	}
+	d.cursorShape = dev
+}
+
+// HandlePointerEnter remembers the window under the pointer and the serial
+// to set its shape with.
+func (d *dropDevice) HandlePointerEnter(ev wl.PointerEnterEvent) {
+	d.pointerFocus = nil
+	if ev.Surface != nil {
+		d.pointerFocus, _ = wl.GetUserData[window.Window](ev.Surface)
+	}
+	d.pointerSerial = ev.Serial
+	d.pointerShown = false
+	d.pointerMoved = false
+}
+
+// HandlePointerLeave forgets the window the pointer left.
+func (d *dropDevice) HandlePointerLeave(ev wl.PointerLeaveEvent) {
+	d.pointerFocus = nil
+	d.pointerShown = false
+}
+
+// showPointer shows shape on the pointer if it is over win. It reports
+// false if it is not, or the shape cannot be shown. The backend shows its
+// default arrow the first time the pointer moves after entering a window, so
+// the shape is shown again for that motion, flagged by moved.
+func (d *dropDevice) showPointer(win *window.Window, shape PointerShape, moved bool) bool {
+	if d.pointer == nil || win == nil || d.pointerFocus != win {
+		return false
+	}
+	if moved && !d.pointerMoved {
+		d.pointerMoved = true
+		d.pointerShown = false
+	}
+	if d.pointerShown && d.pointerShape == shape {
+		return true
+	}
+
+	switch {
+	case shape == PointerHidden:
+		_ = d.pointer.SetCursor(d.pointerSerial, nil, 0, 0)
+	case d.cursorShape != nil:
+		_ = d.cursorShape.setShape(d.pointerSerial, shape.cursorShape())
+	default:
+		if !d.showThemeCursor(shape) {
+			return false
+		}
+	}
+	d.pointerShown, d.pointerShape = true, shape
+	return true
+}
+
+// showThemeCursor shows the first image of the shape's cursor from the
+// cursor theme, for compositors without the cursor-shape-v1 protocol.
+// Animated cursors, such as the wait cursor, are not animated. A shape
+// missing from the theme falls back to the default arrow.
+func (d *dropDevice) showThemeCursor(shape PointerShape) bool {
+	cursor := d.themeCursor(shape)
+	if cursor == nil {
+		cursor = d.themeCursor(PointerDefault)
+	}
+	if cursor == nil || d.compositor == nil {
+		return false
+	}
+	image := cursor.GetCursorImage(0)
+	if image == nil || image.GetBuffer() == nil {
+		return false
+	}
+
+	if d.cursorSurface == nil {
+		surface, err := d.compositor.CreateSurface()
+		if err != nil {
+			Warn("Failed to create the pointer surface: %v", err)
+			return false
+		}
+		d.cursorSurface = surface
+	}
+	_ = d.cursorSurface.Attach(image.GetBuffer(), 0, 0)
+	_ = d.cursorSurface.Damage(0, 0, int32(image.GetWidth()), int32(image.GetHeight()))
+	_ = d.cursorSurface.Commit()
+	_ = d.pointer.SetCursor(d.pointerSerial, d.cursorSurface,
+		int32(image.GetHotspotX()), int32(image.GetHotspotY()))
+	return true
+}
+
+// themeCursor returns the cursor of the shape from the cursor theme, loading
+// the theme on first use, or nil if the theme has none. Missing cursors are
+// remembered, so they are not looked up on every motion.
+func (d *dropDevice) themeCursor(shape PointerShape) *wlcursor.Cursor {
+	if d.cursorTheme == nil {
+		if d.shm == nil || d.themeFailed {
+			return nil
+		}
+		theme, err := wlcursor.LoadTheme(32, d.shm)
+		if err != nil {
+			Warn("Failed to load the cursor theme: %v", err)
+			d.themeFailed = true
+			return nil
+		}
+		d.cursorTheme = theme
+		d.cursors = make(map[PointerShape]*wlcursor.Cursor)
+	}
+
+	if cursor, ok := d.cursors[shape]; ok {
+		return cursor
+	}
+	var cursor *wlcursor.Cursor
+	for _, name := range shape.themeCursors() {
+		if c, err := d.cursorTheme.GetCursor(name); err == nil && c != nil {
+			cursor = c
+			break
+		}
+	}
+	if cursor == nil {
+		Debug("Cursor theme has no cursor for %v", shape)
+	}
+	d.cursors[shape] = cursor
+	return cursor
+}
+
+// destroyPointer releases the pointer, its cursor shape device and surface,
+// and the cursor theme.
+func (d *dropDevice) destroyPointer() {
+	if d.cursorShape != nil {
+		d.cursorShape.destroy()
+		d.cursorShape = nil
+	}
+	if d.cursorSurface != nil {
+		_ = d.cursorSurface.Destroy()
+		d.cursorSurface.Unregister()
+		d.cursorSurface = nil
+	}
+	if d.cursorTheme != nil {
+		for _, cursor := range d.cursorTheme.Cursors {
+			_ = cursor.Destroy()
+		}
+		_ = d.cursorTheme.Destroy()
+		d.cursorTheme, d.cursors = nil, nil
+	}
+	if d.pointer != nil {
+		d.pointer.RemoveEnterHandler(d)
+		d.pointer.RemoveLeaveHandler(d)
+		if d.seatVersion >= 3 {
+			_ = d.pointer.Release()
+		}
+		d.pointer.Unregister()
+		d.pointer = nil
+	}
+	d.pointerFocus = nil
+	d.pointerShown = false
+}
@@ -0,0 +1,145 @@
+package lib
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/neurlang/wayland/window"
+	"github.com/neurlang/wayland/wl"
+)
+
+// newPointerProgram returns a program whose display has a data device with a
+// pointer, and the fake compositor of the display. The pointer is not over
+// the window yet.
+func newPointerProgram(t *testing.T, globals ...wl.RegistryGlobalEvent) (*Program, *dropDevice, *fakeCompositor) {
+	t.Helper()
+	display, server := newFakeCompositor(t)
+	p := NewProgram(nil)
+	renderer, err := NewRenderer(RendererOptions{})
+	if err != nil {
+		t.Fatalf("NewRenderer() error = %v", err)
+	}
+	p.renderer = renderer
+	p.display = display
+	p.window = &window.Window{}
+
+	dropTargets.Lock()
+	acquireDataDevice(display)
+	dev := dropTargets.devices[display]
+	dropTargets.Unlock()
+	t.Cleanup(func() {
+		dropTargets.Lock()
+		releaseDataDevice(display)
+		dropTargets.Unlock()
+	})
+	server.expect(display.Display, 1)
+
+	dev.HandleRegistryGlobal(wl.RegistryGlobalEvent{Name: 1, Interface: "wl_seat", Version: 7})
+	server.skip(1)
+	for _, global := range globals {
+		dev.HandleRegistryGlobal(global)
+		server.skip(1)
+	}
+	dev.HandleSeatCapabilities(wl.SeatCapabilitiesEvent{Capabilities: wl.SeatCapabilityPointer})
+	server.expect(dev.seat, 0)
+	if dev.cursorShapeManager != nil {
+		server.expect(dev.cursorShapeManager, 1)
+	}
+	return p, dev, server
+}
+
+// enterWindow sends the pointer of the device into the window of p.
+func enterWindow(t *testing.T, p *Program, dev *dropDevice, serial uint32) {
+	t.Helper()
+	surface := new(wl.Surface)
+	surface.SetId(0x7f000000)
+	wl.SetUserData(surface, &p.window)
+	t.Cleanup(func() { wl.DeleteUserData(surface) })
+	dev.HandlePointerEnter(wl.PointerEnterEvent{Serial: serial, Surface: surface})
+}
+
+// expectShape reads the next request and fails the test unless it sets the
+// cursor shape with the serial.
+func expectShape(t *testing.T, server *fakeCompositor, dev *dropDevice, serial uint32, shape PointerShape) {
+	t.Helper()
+	req := server.expect(dev.cursorShape, 1)
+	gotSerial := binary.NativeEndian.Uint32(req.args)
+	gotShape := binary.NativeEndian.Uint32(req.args[4:])
+	if gotSerial != serial || gotShape != shape.cursorShape() {
+		t.Errorf("cursor shape %d with serial %d, want %d (%v) with serial %d",
+			gotShape, gotSerial, shape.cursorShape(), shape, serial)
+	}
+}
+
+func TestProgram_SetPointerShapeShowsNow(t *testing.T) {
+	p, dev, server := newPointerProgram(t,
+		wl.RegistryGlobalEvent{Name: 2, Interface: cursorShapeInterface, Version: 1})
+	if dev.cursorShape == nil {
+		t.Fatal("pointer has no cursor shape device")
+	}
+
+	// The pointer is not over the window, so the backend shows the shape
+	if cursor := p.Motion(nil, nil, 0, 1, 1); cursor != window.CursorLeftPtr {
+		t.Errorf("Motion() outside the window = %d, want %d", cursor, window.CursorLeftPtr)
+	}
+
+	enterWindow(t, p, dev, 7)
+	p.handleProgramMsg(SetPointerShape(PointerWait)())
+	expectShape(t, server, dev, 7, PointerWait)
+
+	// The first motion shows the shape again over the backend's arrow, and
+	// the backend is told to leave the pointer alone
+	if cursor := p.Motion(nil, nil, 0, 1, 1); cursor != window.CursorUnset {
+		t.Errorf("Motion() = %d, want %d", cursor, window.CursorUnset)
+	}
+	expectShape(t, server, dev, 7, PointerWait)
+	p.Motion(nil, nil, 0, 2, 2)
+
+	// The crosshair comes from the compositor, and hiding the pointer
+	// clears its surface. The motion above sent no request in between.
+	p.handleProgramMsg(SetPointerShape(PointerCrosshair)())
+	expectShape(t, server, dev, 7, PointerCrosshair)
+	p.handleProgramMsg(SetPointerShape(PointerHidden)())
+	if req := server.expect(dev.pointer, 0); binary.NativeEndian.Uint32(req.args[4:]) != 0 {
+		t.Error("hidden pointer has a surface")
+	}
+
+	dev.HandlePointerLeave(wl.PointerLeaveEvent{Serial: 8})
+	p.handleProgramMsg(SetPointerShape(PointerText)())
+	if cursor := p.Motion(nil, nil, 0, 1, 1); cursor != window.CursorIbeam {
+		t.Errorf("Motion() after the pointer left = %d, want %d", cursor, window.CursorIbeam)
+	}
+}
+
+// TestProgram_PointerWithoutCursorTheme tests that the backend shows the
+// pointer shape when the compositor has no cursor-shape-v1 protocol and no
+// cursor theme can be loaded.
+func TestProgram_PointerWithoutCursorTheme(t *testing.T) {
+	p, dev, _ := newPointerProgram(t)
+	enterWindow(t, p, dev, 3)
+
+	if cursor := p.Motion(nil, nil, 0, 1, 1); cursor != window.CursorLeftPtr {
+		t.Errorf("Motion() = %d, want %d", cursor, window.CursorLeftPtr)
+	}
+	p.handleProgramMsg(SetPointerShape(PointerHand)())
+	if cursor := p.Motion(nil, nil, 0, 1, 1); cursor != window.CursorHand1 {
+		t.Errorf("Motion() = %d, want %d", cursor, window.CursorHand1)
+	}
+}
+
+// TestProgram_EmbeddedPointer tests that embedded programs leave the pointer
+// to the host.
+func TestProgram_EmbeddedPointer(t *testing.T) {
+	p, dev, _ := newPointerProgram(t,
+		wl.RegistryGlobalEvent{Name: 2, Interface: cursorShapeInterface, Version: 1})
+	enterWindow(t, p, dev, 7)
+	p.embedded = true
+	p.pointerShape = PointerWait
+
+	if cursor := p.Motion(nil, nil, 0, 1, 1); cursor != window.CursorWatch {
+		t.Errorf("Motion() = %d, want %d", cursor, window.CursorWatch)
+	}
+	if dev.pointerShown {
+		t.Error("embedded program set the pointer of the host")
+	}
+}
@@ -0,0 +1,82 @@
+package lib
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/neurlang/wayland/window"
+)
+
+func TestSetPointerShape(t *testing.T) {
+	msg, ok := SetPointerShape(PointerWait)().(setPointerShapeMsg)
+	if !ok {
+		t.Fatalf("SetPointerShape returned %T, want setPointerShapeMsg", SetPointerShape(PointerWait)())
+	}
+	if msg.shape != PointerWait {
+		t.Errorf("SetPointerShape set shape %v, want %v", msg.shape, PointerWait)
+	}
+}
+
+func TestMarkWithPointer_InnermostShape(t *testing.T) {
+	view := MarkWithPointer("editor", PointerText,
+		"edit "+MarkWithPointer("link", PointerHand, "here")+" "+Mark("plain", "word"))
+	grid := ParseANSI(view, 20, 1)
+
+	tests := []struct {
+		x    int
+		want PointerShape
+	}{
+		{0, PointerText},
+		{6, PointerHand},
+		{11, PointerText},
+		{18, PointerDefault},
+	}
+	for _, tt := range tests {
+		if got := grid.pointerAt(tt.x, 0); got != tt.want {
+			t.Errorf("pointerAt(%d) = %v, want %v", tt.x, got, tt.want)
+		}
+	}
+
+	if got := grid.ZoneAt(6, 0); !reflect.DeepEqual(got, []string{"link", "editor"}) {
+		t.Errorf("ZoneAt(6, 0) = %v, want [link editor]", got)
+	}
+}
+
+func TestProgram_PointerShapeAt(t *testing.T) {
+	p := NewProgram(nil)
+	p.grid = ParseANSI(MarkWithPointer("link", PointerHand, "link"), 10, 1)
+
+	if got := p.pointerShapeAt(1, 0); got != PointerHand {
+		t.Errorf("shape over link = %v, want %v", got, PointerHand)
+	}
+	if got := p.pointerShapeAt(6, 0); got != PointerDefault {
+		t.Errorf("shape outside link = %v, want %v", got, PointerDefault)
+	}
+
+	// A shape set with SetPointerShape applies everywhere
+	p.handleProgramMsg(SetPointerShape(PointerWait)())
+	if got := p.pointerShapeAt(1, 0); got != PointerWait {
+		t.Errorf("shape over link while busy = %v, want %v", got, PointerWait)
+	}
+
+	p.handleProgramMsg(SetPointerShape(PointerDefault)())
+	if got := p.pointerShapeAt(1, 0); got != PointerHand {
+		t.Errorf("shape over link after reset = %v, want %v", got, PointerHand)
+	}
+}
+
+func TestPointerShape_Cursor(t *testing.T) {
+	tests := []struct {
+		shape PointerShape
+		want  int
+	}{
+		{PointerDefault, window.CursorLeftPtr},
+		{PointerText, window.CursorIbeam},
+		{PointerHand, window.CursorHand1},
+	}
+	for _, tt := range tests {
+		if got := tt.shape.cursor(); got != tt.want {
+			t.Errorf("%v.cursor() = %d, want %d", tt.shape, got, tt.want)
+		}
+	}
+}
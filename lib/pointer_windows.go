@@ -0,0 +1,26 @@
+package lib
+
+import "github.com/neurlang/wayland/window"
+
+// cursor returns the window system cursor for the pointer shape.
+// The Windows backend only knows the arrow, I-beam and hand cursors; other
+// shapes fall back to the arrow.
+func (s PointerShape) cursor() int {
+	switch s {
+	case PointerText:
+		return window.CursorIbeam
+	case PointerHand:
+		return window.CursorHand1
+	}
+	return window.CursorLeftPtr
+}
+
+// pointerCursor returns the cursor the backend should show for the pointer
+// shape.
+func (p *Program) pointerCursor(shape PointerShape, moved bool) int {
+	return shape.cursor()
+}
+
+// showPointerShape does nothing on Windows, where the pointer shape changes
+// when the pointer next moves.
+func (p *Program) showPointerShape() {}
@@ -0,0 +1,137 @@
+package lib
+
+import (
+	"github.com/neurlang/wayland/wl"
+)
+
+// The primary selection, pasted with the middle button, is set through the
+// zwp_primary_selection_unstable_v1 protocol, which the wl package has no
+// bindings for. These proxies implement the part of it needed to offer text.
+
+// primarySelectionInterface is the interface name of the manager global.
+const primarySelectionInterface = "zwp_primary_selection_device_manager_v1"
+
+// primarySelectionManager is a zwp_primary_selection_device_manager_v1.
+type primarySelectionManager struct {
+	wl.BaseProxy
+}
+
+// newPrimarySelectionManager registers a manager to be bound.
+func newPrimarySelectionManager(ctx *wl.Context) *primarySelectionManager {
+	m := new(primarySelectionManager)
+	ctx.Register(m)
+	return m
+}
+
+// createSource creates a source for text offered on the primary selection.
+func (m *primarySelectionManager) createSource() (*primarySource, error) {
+	src := new(primarySource)
+	m.Context().Register(src)
+	return src, m.Context().SendRequest(m, 0, src)
+}
+
+// getDevice creates the primary selection device of a seat.
+func (m *primarySelectionManager) getDevice(seat *wl.Seat) (*primarySelectionDevice, error) {
+	dev := new(primarySelectionDevice)
+	m.Context().Register(dev)
+	return dev, m.Context().SendRequest(m, 1, dev, seat)
+}
+
+// destroy destroys the manager.
+func (m *primarySelectionManager) destroy() {
+	_ = m.Context().SendRequest(m, 2)
+	m.Unregister()
+}
+
+// Dispatch implements wl.Dispatcher. The manager has no events.
+func (m *primarySelectionManager) Dispatch(ev *wl.Event) {}
+
+// primarySelectionDevice is a zwp_primary_selection_device_v1.
+type primarySelectionDevice struct {
+	wl.BaseProxy
+
+	// offers are the offers of other applications, which are destroyed
+	// once the selection they were made for is announced
+	offers []*primarySelectionOffer
+}
+
+// setSelection offers the text of src on the primary selection.
+func (d *primarySelectionDevice) setSelection(src *primarySource, serial uint32) error {
+	return d.Context().SendRequest(d, 0, src, serial)
+}
+
+// destroy destroys the device and the offers it still holds.
+func (d *primarySelectionDevice) destroy() {
+	d.dropOffers()
+	_ = d.Context().SendRequest(d, 1)
+	d.Unregister()
+}
+
+// dropOffers destroys the offers made to the device. The program does not
+// paste the primary selection, so it never reads them.
+func (d *primarySelectionDevice) dropOffers() {
+	for _, o := range d.offers {
+		_ = o.Context().SendRequest(o, 1)
+		o.Unregister()
+	}
+	d.offers = nil
+}
+
+// Dispatch implements wl.Dispatcher for the data_offer and selection events.
+func (d *primarySelectionDevice) Dispatch(ev *wl.Event) {
+	switch ev.Opcode {
+	case 0:
+		o := new(primarySelectionOffer)
+		ev.NewId(o, d.Context())
+		d.offers = append(d.offers, o)
+	case 1:
+		d.dropOffers()
+	}
+}
+
+// primarySelectionOffer is a zwp_primary_selection_offer_v1 of another
+// application.
+type primarySelectionOffer struct {
+	wl.BaseProxy
+}
+
+// Dispatch implements wl.Dispatcher. The formats of the offer are not used.
+func (o *primarySelectionOffer) Dispatch(ev *wl.Event) {}
+
+// primarySource is a zwp_primary_selection_source_v1 serving copied text.
+type primarySource struct {
+	wl.BaseProxy
+	text string
+}
+
+// offer announces a format the text can be pasted in.
+func (s *primarySource) offer(mime string) error {
+	return s.Context().SendRequest(s, 0, mime)
+}
+
+// destroy stops serving the text and releases the source. It can be called
+// more than once.
+func (s *primarySource) destroy() {
+	if s.Context() == nil {
+		return
+	}
+	_ = s.Context().SendRequest(s, 1)
+	s.Unregister()
+	s.SetContext(nil)
+}
+
+// Dispatch implements wl.Dispatcher for the send and cancelled events.
+func (s *primarySource) Dispatch(ev *wl.Event) {
+	switch ev.Opcode {
+	case 0:
+		_ = ev.String()
+		fd, err := ev.FD()
+		if err != nil {
+			Warn("Failed to send primary selection data: %v", err)
+			return
+		}
+		writeSelection(fd, s.text)
+	case 1:
+		s.destroy()
+	}
+}
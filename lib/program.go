@@ -3,11 +3,15 @@ package lib
 import (
 	"context"
 	"fmt"
+	"path/filepath"
+	"reflect"
 	"runtime/debug"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/neurlang/wayland/window"
+	"github.com/neurlang/wayland/xdg"
 	"github.com/neurlang/wayland/wl"
 )
 
@@ -18,15 +22,33 @@ type Program struct {
 	window  *window.Window
 	widget  *window.Widget
 
+	// toplevel is the xdg toplevel of the window, found by findToplevel
+	toplevel *xdg.Toplevel
+
 	msgChan  chan Msg
+	queue    *messageQueue
 	cmdChan  chan Cmd
 	quitChan chan struct{}
+	quitOnce sync.Once
+	// wakeups holds the functions wakeDisplay left for the next frame, on
+	// backends whose display loop cannot be woken
+	wakeups  []func()
+	killed   atomic.Bool
+	panicErr *PanicError
+	done     chan struct{}
+	doneOnce sync.Once
 
 	ctx    context.Context
 	cancel context.CancelFunc
 
 	options  ProgramOptions
 	mu       sync.Mutex
+
+	// updateFn and viewFn call the model's Update and View through the
+	// middleware
+	updateFn UpdateFunc
+	viewFn   ViewFunc
+
 	renderer *Renderer
 	cmdExec  *CommandExecutor
 
@@ -44,10 +66,65 @@ type Program struct {
 	motionPending     bool
 	pendingMotionX    int
 	pendingMotionY    int
+	mouseMode         MouseMode
+	heldButton        MouseButton
+	clicks            *clickTracker
+	scroll            scrollAccumulator
+	kinetic           kineticScroller
+	axisSource        uint32
+	touchPoints       map[int32]touchPoint
+	touchPrimary      int32
+	touchEmulating    bool
+	touchScrolling    bool
+	touchScrollIDs    [2]int32
+	touchScrollCenter touchPoint
+	gestures          *gestureRecognizer
+	pointerInside     bool
+	focused           bool
+	grid              *TerminalGrid
+	gridMu            sync.Mutex
+	renderMu          sync.Mutex // guards the renderer, for Screenshot
+	selection         selection
+	selectionChanged  bool
+	dragActive        bool
+	pointerShape      PointerShape
+	windowState       WindowStateMsg
+	minCols           int
+	minRows           int
+	maxCols           int
+	maxRows           int
+	originX           int32
+	originY           int32
+	pixelWidth        int32
+	pixelHeight       int32
+	inlineSized       bool
+
+	// id identifies a secondary window, main is the program of the main
+	// window and opener the program whose model opened it. They are unset
+	// for the main window. windows holds the open secondary windows of
+	// the main program.
+	id        WindowID
+	main      *Program
+	opener    *Program
+	windows   map[WindowID]*Program
+	windowsMu sync.Mutex
+
+	// embedded is set for a program drawn into part of a window owned by
+	// the caller, within bounds. hostWidget is the widget the host last
+	// forwarded a redraw for, and hostDisplay the display it copies through
+	// until it is detached.
+	embedded    bool
+	bounds      window.Rectangle
+	hostWidget  *window.Widget
+	hostDisplay *window.Display
 }
 
 // ProgramOptions configures the Program's appearance and behavior.
 type ProgramOptions struct {
+	// Context is the parent context of the program. Cancelling it kills
+	// the program. A nil Context means context.Background.
+	Context context.Context
+
 	// FontFamily specifies the font family to use for rendering text.
 	FontFamily string
 
@@ -60,17 +137,143 @@ type ProgramOptions struct {
 	// InitialHeight specifies the initial window height in pixels.
 	InitialHeight int32
 
+	// InitialCols and InitialRows specify the initial window size in
+	// cells. When both are set they take precedence over InitialWidth and
+	// InitialHeight.
+	InitialCols int
+	InitialRows int
+
+	// SnapToCells makes the window resize in whole cells, so there is no
+	// leftover space around the grid other than the padding.
+	SnapToCells bool
+
+	// Padding specifies the space in pixels kept free around the grid on
+	// every edge. It is filled with the theme background color.
+	Padding int32
+
+	// ContentPlacement specifies where the grid is placed when the window
+	// is not an exact multiple of the cell size. The leftover space is
+	// filled with the theme background color.
+	ContentPlacement ContentPlacement
+
 	// WindowTitle specifies the text displayed in the window's title bar.
 	WindowTitle string
 
 	// FPS specifies the maximum frames per second for rendering.
 	// A value of 0 means no limit.
 	FPS int
+
+	// MouseMode specifies which mouse events are delivered to Update.
+	// It can be changed at runtime with the EnableMouseCellMotion,
+	// EnableMouseAllMotion and DisableMouse commands.
+	MouseMode MouseMode
+
+	// DoubleClickInterval specifies the maximum time between two presses
+	// for them to count as a double (or triple) click.
+	DoubleClickInterval time.Duration
+
+	// DoubleClickDistance specifies the maximum distance in cells between
+	// two presses for them to count as a double (or triple) click.
+	DoubleClickDistance int
+
+	// ScrollUnitsPerLine specifies how much touchpad or continuous axis
+	// motion makes up one line of scrolling. A value of 0 uses the cell
+	// height, so content follows the fingers. Wheel notches always scroll
+	// one line each.
+	ScrollUnitsPerLine float32
+
+	// KineticScroll enables momentum scrolling after a touchpad fling.
+	KineticScroll bool
+
+	// TouchMouseEmulation makes single-finger touches press, drag and
+	// release the left mouse button, and two-finger drags scroll, in
+	// addition to delivering TouchMsg values.
+	TouchMouseEmulation bool
+
+	// Gestures enables recognition of taps, long presses, and two-finger
+	// swipes and pinches, delivered as GestureMsg values.
+	Gestures bool
+
+	// ReportFocus enables FocusMsg and BlurMsg when the window gains or
+	// loses keyboard focus.
+	ReportFocus bool
+
+	// Theme specifies the default text and background colors and the
+	// colors of selected text.
+	Theme Theme
+
+	// Inline makes the window height follow the number of lines of View,
+	// like a Bubble Tea program that does not use the alternate screen.
+	Inline bool
+
+	// InlineWidth makes the window width also follow the widest line of
+	// View. It has no effect unless Inline is set.
+	InlineWidth bool
+
+	// MinCols, MinRows, MaxCols and MaxRows limit the window size in cells.
+	// A value of 0 means no limit. They can be changed at runtime with the
+	// SetMinSize and SetMaxSize commands.
+	MinCols int
+	MinRows int
+	MaxCols int
+	MaxRows int
+
+	// MouseSelection enables the built-in text selection: Shift+drag, or
+	// drag while mouse reporting is off, selects text, double-click selects
+	// a word and triple-click a line. Selected text is copied to the
+	// clipboard and the primary selection.
+	MouseSelection bool
+
+	// QueueSize specifies how many messages can wait for Update before the
+	// message policies apply.
+	QueueSize int
+
+	// MaxWorkers limits how many commands run at once. Further commands
+	// wait in a queue, in order, for a running one to finish. A value of 0
+	// means no limit.
+	MaxWorkers int
+
+	// MessagePolicies specifies, by message type, what happens to a
+	// message when the queue is full. Types that are not listed use
+	// PolicyWait. Set entries with WithMessagePolicy.
+	MessagePolicies map[reflect.Type]MessagePolicy
+
+	// Clock is the source of time for Tick, Every, subscription
+	// restarts, long presses and kinetic scrolling. Tests set a FakeClock
+	// to control time.
+	Clock Clock
+
+	// Filters intercept messages before Update. Add them with WithFilter.
+	Filters []func(Model, Msg) Msg
+
+	// Middleware wraps the model's Update and View. Add it with
+	// WithMiddleware.
+	Middleware []Middleware
+
+	// ShutdownTimeout specifies how long Run waits for running commands to
+	// finish after the program quits. Commands still running then are
+	// reported in a *LeakedCommandsError. A value of 0, the default, waits
+	// until they finish.
+	ShutdownTimeout time.Duration
+
+	// ScreenshotDir enables the Print Screen key, which saves the window
+	// as a timestamped PNG file in this directory instead of sending the
+	// key to Update. An empty directory disables it.
+	ScreenshotDir string
 }
 
 // ProgramOption is a function that configures a Program.
 type ProgramOption func(*ProgramOptions)
 
+// WithContext sets a context that kills the program when it is cancelled.
+// Run then returns an error wrapping ErrProgramKilled and the context's
+// error.
+func WithContext(ctx context.Context) ProgramOption {
+	return func(opts *ProgramOptions) {
+		opts.Context = ctx
+	}
+}
+
 // WithFontFamily sets the font family for text rendering.
 func WithFontFamily(family string) ProgramOption {
 	return func(opts *ProgramOptions) {
@@ -93,6 +296,65 @@ func WithInitialSize(width, height int32) ProgramOption {
 	}
 }
 
+// WithInitialCells sets the initial window size in cells. The window is
+// sized to fit exactly cols by rows cells plus padding.
+func WithInitialCells(cols, rows int) ProgramOption {
+	return func(opts *ProgramOptions) {
+		opts.InitialCols = cols
+		opts.InitialRows = rows
+	}
+}
+
+// WithCellSnapping makes the window resize in whole cells.
+func WithCellSnapping() ProgramOption {
+	return func(opts *ProgramOptions) {
+		opts.SnapToCells = true
+	}
+}
+
+// WithPadding sets the space in pixels kept free around the grid.
+func WithPadding(pixels int32) ProgramOption {
+	return func(opts *ProgramOptions) {
+		opts.Padding = pixels
+	}
+}
+
+// WithContentPlacement sets where the grid is placed when the window is not
+// an exact multiple of the cell size.
+func WithContentPlacement(placement ContentPlacement) ProgramOption {
+	return func(opts *ProgramOptions) {
+		opts.ContentPlacement = placement
+	}
+}
+
+// WithInline makes the window height follow the number of lines of View,
+// so small prompts open as compact windows. If widthToo is true, the window
+// width follows the widest line as well.
+func WithInline(widthToo bool) ProgramOption {
+	return func(opts *ProgramOptions) {
+		opts.Inline = true
+		opts.InlineWidth = widthToo
+	}
+}
+
+// WithMinSize sets the minimum window size in cells. A value of 0 means no
+// limit.
+func WithMinSize(cols, rows int) ProgramOption {
+	return func(opts *ProgramOptions) {
+		opts.MinCols = cols
+		opts.MinRows = rows
+	}
+}
+
+// WithMaxSize sets the maximum window size in cells. A value of 0 means no
+// limit.
+func WithMaxSize(cols, rows int) ProgramOption {
+	return func(opts *ProgramOptions) {
+		opts.MaxCols = cols
+		opts.MaxRows = rows
+	}
+}
+
 // WithWindowTitle sets the window title.
 func WithWindowTitle(title string) ProgramOption {
 	return func(opts *ProgramOptions) {
@@ -107,6 +369,154 @@ func WithFPS(fps int) ProgramOption {
 	}
 }
 
+// WithMouseCellMotion enables mouse clicks, wheel events and motion while
+// a button is held (dragging).
+// This matches Bubble Tea's WithMouseCellMotion option for compatibility.
+func WithMouseCellMotion() ProgramOption {
+	return func(opts *ProgramOptions) {
+		opts.MouseMode = MouseModeCellMotion
+	}
+}
+
+// WithMouseAllMotion enables mouse clicks, wheel events and all motion,
+// including motion without a button held (hovering). This is the default.
+// This matches Bubble Tea's WithMouseAllMotion option for compatibility.
+func WithMouseAllMotion() ProgramOption {
+	return func(opts *ProgramOptions) {
+		opts.MouseMode = MouseModeAllMotion
+	}
+}
+
+// WithoutMouse disables the delivery of all mouse events.
+func WithoutMouse() ProgramOption {
+	return func(opts *ProgramOptions) {
+		opts.MouseMode = MouseModeNone
+	}
+}
+
+// WithDoubleClickInterval sets the maximum time between presses that are
+// counted as a double or triple click.
+func WithDoubleClickInterval(d time.Duration) ProgramOption {
+	return func(opts *ProgramOptions) {
+		opts.DoubleClickInterval = d
+	}
+}
+
+// WithDoubleClickDistance sets the maximum distance in cells between presses
+// that are counted as a double or triple click.
+func WithDoubleClickDistance(cells int) ProgramOption {
+	return func(opts *ProgramOptions) {
+		opts.DoubleClickDistance = cells
+	}
+}
+
+// WithScrollUnitsPerLine sets how much touchpad or continuous axis motion
+// makes up one line of scrolling. A value of 0 uses the cell height.
+func WithScrollUnitsPerLine(units float32) ProgramOption {
+	return func(opts *ProgramOptions) {
+		opts.ScrollUnitsPerLine = units
+	}
+}
+
+// WithKineticScroll enables momentum scrolling: after a touchpad fling,
+// wheel messages keep arriving with decaying speed.
+func WithKineticScroll() ProgramOption {
+	return func(opts *ProgramOptions) {
+		opts.KineticScroll = true
+	}
+}
+
+// WithTouchMouseEmulation makes touch input also drive the mouse: a single
+// finger presses, drags and releases the left button, and two fingers scroll.
+func WithTouchMouseEmulation() ProgramOption {
+	return func(opts *ProgramOptions) {
+		opts.TouchMouseEmulation = true
+	}
+}
+
+// WithGestures enables recognition of taps, long presses, and two-finger
+// swipes and pinches, delivered as GestureMsg values.
+func WithGestures() ProgramOption {
+	return func(opts *ProgramOptions) {
+		opts.Gestures = true
+	}
+}
+
+// WithReportFocus enables FocusMsg and BlurMsg when the window gains or
+// loses keyboard focus.
+// This matches Bubble Tea's WithReportFocus option for compatibility.
+func WithReportFocus() ProgramOption {
+	return func(opts *ProgramOptions) {
+		opts.ReportFocus = true
+	}
+}
+
+// WithTheme sets the default colors and the selection colors.
+func WithTheme(theme Theme) ProgramOption {
+	return func(opts *ProgramOptions) {
+		opts.Theme = theme
+	}
+}
+
+// WithoutMouseSelection disables the built-in text selection, for models
+// that handle Shift+drag themselves.
+func WithoutMouseSelection() ProgramOption {
+	return func(opts *ProgramOptions) {
+		opts.MouseSelection = false
+	}
+}
+
+// WithShutdownTimeout sets how long Run waits for running commands to finish
+// after the program quits. By default it waits until they finish.
+func WithShutdownTimeout(d time.Duration) ProgramOption {
+	return func(opts *ProgramOptions) {
+		opts.ShutdownTimeout = d
+	}
+}
+
+// WithScreenshotDir makes the Print Screen key save screenshots to dir.
+func WithScreenshotDir(dir string) ProgramOption {
+	return func(opts *ProgramOptions) {
+		opts.ScreenshotDir = dir
+	}
+}
+
+// WithClock sets the source of time for Tick, Every, subscription
+// restarts, long presses and kinetic scrolling, such as a FakeClock in
+// tests.
+func WithClock(clock Clock) ProgramOption {
+	return func(opts *ProgramOptions) {
+		opts.Clock = clock
+	}
+}
+
+// WithQueueSize sets how many messages can wait for Update before the
+// message policies apply.
+func WithQueueSize(size int) ProgramOption {
+	return func(opts *ProgramOptions) {
+		opts.QueueSize = size
+	}
+}
+
+// WithMaxWorkers limits how many commands run at once.
+func WithMaxWorkers(n int) ProgramOption {
+	return func(opts *ProgramOptions) {
+		opts.MaxWorkers = n
+	}
+}
+
+// WithMessagePolicy sets what happens to messages of the same type as msg
+// when the queue is full. For example, WithMessagePolicy(MouseMsg{},
+// PolicyKeepLatest) delivers only the latest mouse event after a stall.
+func WithMessagePolicy(msg Msg, policy MessagePolicy) ProgramOption {
+	return func(opts *ProgramOptions) {
+		if opts.MessagePolicies == nil {
+			opts.MessagePolicies = make(map[reflect.Type]MessagePolicy)
+		}
+		opts.MessagePolicies[reflect.TypeOf(msg)] = policy
+	}
+}
+
 // NewProgram creates a new Program with the given model and options.
 // This function matches Bubble Tea's NewProgram API for compatibility.
 func NewProgram(model Model, opts ...ProgramOption) *Program {
@@ -117,28 +527,56 @@ func NewProgram(model Model, opts ...ProgramOption) *Program {
 		InitialHeight: 600,
 		WindowTitle:   "BubbleGum Application",
 		FPS:           60,
+		MouseMode:     MouseModeAllMotion,
+
+		DoubleClickInterval: 500 * time.Millisecond,
+		DoubleClickDistance: 1,
+
+		Theme:          DefaultTheme(),
+		MouseSelection: true,
+
+		Clock:           RealClock(),
+		QueueSize:       100,
+		MessagePolicies: defaultMessagePolicies(),
 	}
 
 	for _, opt := range opts {
 		opt(&options)
 	}
 
-	ctx, cancel := context.WithCancel(context.Background())
+	parent := options.Context
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithCancel(parent)
+	updateFn, viewFn := chainMiddleware(options.Middleware)
 
 	return &Program{
-		model:    model,
-		msgChan:  make(chan Msg, 100),
-		cmdChan:  make(chan Cmd, 100),
-		quitChan: make(chan struct{}),
-		ctx:      ctx,
-		cancel:   cancel,
-		options:  options,
+		model:     model,
+		msgChan:   make(chan Msg, max(options.QueueSize, 0)),
+		queue:     newMessageQueue(options.MessagePolicies),
+		updateFn:  updateFn,
+		viewFn:    viewFn,
+		cmdChan:   make(chan Cmd, 100),
+		quitChan:  make(chan struct{}),
+		done:      make(chan struct{}),
+		ctx:       ctx,
+		cancel:    cancel,
+		options:   options,
+		mouseMode: options.MouseMode,
+		clicks:    newClickTracker(options.DoubleClickInterval, options.DoubleClickDistance),
+		minCols:   options.MinCols,
+		minRows:   options.MinRows,
+		maxCols:   options.MaxCols,
+		maxRows:   options.MaxRows,
 	}
 }
 
 // Run starts the program and blocks until it exits.
 // It returns the final model state and any error that occurred.
-func (p *Program) Run() (Model, error) {
+func (p *Program) Run() (_ Model, err error) {
+	defer p.markDone()
+
 	Info("Starting BubbleGum application")
 	Debug("Configuration: %+v", p.options)
 	
@@ -156,13 +594,47 @@ func (p *Program) Run() (Model, error) {
 	p.display = display
 	defer p.display.Destroy()
 
+	// Create the main window and start the model
+	if err := p.createWindow(); err != nil {
+		p.destroyWindow()
+		return p.model, err
+	}
+	defer p.destroyWindow()
+	defer func() {
+		if leakErr := p.shutdownCommands(); err == nil {
+			err = leakErr
+		}
+	}()
+	defer p.closeWindows()
+	p.startModel()
+	if p.panicErr != nil {
+		return p.model, p.panicErr
+	}
+
+	// Stop the loop when Quit or Kill is called from another goroutine,
+	// or the context is cancelled
+	stopWatching := p.watchExit()
+
+	Info("Starting event loop")
+	// Run the display event loop (blocks until quit)
+	window.DisplayRun(display)
+	stopWatching()
+
+	Info("Application exited")
+	return p.model, p.exitError()
+}
+
+// createWindow creates the window, widget, renderer and command executor
+// of the program on its display, and schedules the initial resize.
+// Resources created before an error are released by destroyWindow.
+func (p *Program) createWindow() error {
 	Debug("Creating window")
 	// Create window
-	p.window = window.Create(display)
+	p.window = window.Create(p.display)
 	if p.window == nil {
-		return p.model, fmt.Errorf("failed to create window: window.Create returned nil")
+		return fmt.Errorf("failed to create window: window.Create returned nil")
 	}
-	defer p.window.Destroy()
+	p.findToplevel()
 
 	// Set window title
 	Debug("Setting window title: %s", p.options.WindowTitle)
@@ -176,36 +648,59 @@ func (p *Program) Run() (Model, error) {
 	// Create widget
 	p.widget = p.window.AddWidget(p)
 	if p.widget == nil {
-		return p.model, fmt.Errorf("failed to create widget: AddWidget returned nil")
+		return fmt.Errorf("failed to create widget: AddWidget returned nil")
 	}
-	defer p.widget.Destroy()
 
 	// Set up keyboard handler
 	Debug("Setting up keyboard handler")
 	p.window.SetKeyboardHandler(p)
 
+	Debug("Setting up drag and drop")
+	p.setupDragAndDrop()
+	p.watchState()
+
+	if err := p.createRenderer(); err != nil {
+		return err
+	}
+
+	// Create input handler (note: Input is created by the window system, not by us)
+	// We'll get it from event handlers
+
+	// Schedule initial resize
+	initialWidth, initialHeight := p.options.InitialWidth, p.options.InitialHeight
+	if p.options.InitialCols > 0 && p.options.InitialRows > 0 {
+		initialWidth, initialHeight = windowSizeFor(p.options.InitialCols, p.options.InitialRows,
+			p.renderer.CellWidth(), p.renderer.CellHeight(), p.options.Padding)
+	}
+	Debug("Scheduling initial resize: %dx%d", initialWidth, initialHeight)
+	p.widget.ScheduleResize(initialWidth, initialHeight)
+
+	return nil
+}
+
+// createRenderer creates the renderer and the command executor.
+func (p *Program) createRenderer() error {
 	Debug("Creating renderer")
 	// Create renderer
+	var err error
 	p.renderer, err = NewRenderer(RendererOptions{
-		DefaultFg: NewColor(255, 255, 255),
-		DefaultBg: NewColor(0, 0, 0),
+		DefaultFg: p.options.Theme.Foreground,
+		DefaultBg: p.options.Theme.Background,
 	})
 	if err != nil {
-		return p.model, fmt.Errorf("failed to create renderer: %w", err)
+		return fmt.Errorf("failed to create renderer: %w", err)
 	}
 
 	Debug("Creating command executor")
 	// Create command executor
-	p.cmdExec = NewCommandExecutor(p.ctx, p.msgChan)
-	defer p.cmdExec.Shutdown()
-
-	// Create input handler (note: Input is created by the window system, not by us)
-	// We'll get it from event handlers
-
-	// Schedule initial resize
-	Debug("Scheduling initial resize: %dx%d", p.options.InitialWidth, p.options.InitialHeight)
-	p.widget.ScheduleResize(p.options.InitialWidth, p.options.InitialHeight)
+	p.cmdExec = newCommandExecutor(p.ctx, p.msgChan, p.queue, p.options.MaxWorkers)
+	p.cmdExec.SetClock(p.options.Clock)
+	return nil
+}
 
+// startModel calls the model's Init, executes its command and schedules the
+// first frame.
+func (p *Program) startModel() {
 	Debug("Calling model Init()")
 	// Call model's Init() with panic recovery
 	var initialCmd Cmd
@@ -213,14 +708,19 @@ func (p *Program) Run() (Model, error) {
 		defer func() {
 			if r := recover(); r != nil {
 				Error("Panic in Init(): %v", r)
-				// Log stack trace
-				Error("Stack trace: %v", getStackTrace())
+				stack := getStackTrace()
+				Error("Stack trace: %v", stack)
+				p.recordPanic(r, stack)
 				// Don't execute any command if Init panicked
 				initialCmd = nil
 			}
 		}()
 		initialCmd = p.model.Init()
 	}()
+	if p.panicErr != nil {
+		p.quit()
+		return
+	}
 	
 	if initialCmd != nil {
 		Debug("Executing initial command")
@@ -231,13 +731,18 @@ func (p *Program) Run() (Model, error) {
 	p.mu.Lock()
 	p.scheduleRedraw()
 	p.mu.Unlock()
+}
 
-	Info("Starting event loop")
-	// Run the display event loop (blocks until quit)
-	window.DisplayRun(display)
-
-	Info("Application exited")
-	return p.model, nil
+// destroyWindow destroys the widget and the window, in reverse order of
+// creation.
+func (p *Program) destroyWindow() {
+	p.teardownDragAndDrop()
+	if p.widget != nil {
+		p.widget.Destroy()
+	}
+	if p.window != nil {
+		p.window.Destroy()
+	}
 }
 
 // validateOptions validates the program configuration options.
@@ -248,6 +753,27 @@ func (p *Program) validateOptions() error {
 	if p.options.InitialHeight <= 0 {
 		return fmt.Errorf("initial height must be positive, got %d", p.options.InitialHeight)
 	}
+	if p.options.InitialCols < 0 || p.options.InitialRows < 0 {
+		return fmt.Errorf("initial size in cells must not be negative, got %dx%d", p.options.InitialCols, p.options.InitialRows)
+	}
+	if p.options.MinCols < 0 || p.options.MinRows < 0 || p.options.MaxCols < 0 || p.options.MaxRows < 0 {
+		return fmt.Errorf("size limits must not be negative")
+	}
+	if p.options.Clock == nil {
+		return fmt.Errorf("clock must not be nil")
+	}
+	if p.options.QueueSize < 1 {
+		return fmt.Errorf("queue size must be positive, got %d", p.options.QueueSize)
+	}
+	if p.options.MaxWorkers < 0 {
+		return fmt.Errorf("max workers must be non-negative, got %d", p.options.MaxWorkers)
+	}
+	if p.options.ShutdownTimeout < 0 {
+		return fmt.Errorf("shutdown timeout must be non-negative, got %v", p.options.ShutdownTimeout)
+	}
+	if p.options.Padding < 0 {
+		return fmt.Errorf("padding must be non-negative, got %d", p.options.Padding)
+	}
 	if p.options.FontSize <= 0 {
 		return fmt.Errorf("font size must be positive, got %d", p.options.FontSize)
 	}
@@ -260,11 +786,40 @@ func (p *Program) validateOptions() error {
 	if p.options.FontFamily == "" {
 		return fmt.Errorf("font family cannot be empty")
 	}
+	if p.options.DoubleClickInterval < 0 {
+		return fmt.Errorf("double-click interval must be non-negative, got %v", p.options.DoubleClickInterval)
+	}
+	if p.options.ScrollUnitsPerLine < 0 {
+		return fmt.Errorf("scroll units per line must be non-negative, got %v", p.options.ScrollUnitsPerLine)
+	}
+	if p.options.DoubleClickDistance < 0 {
+		return fmt.Errorf("double-click distance must be non-negative, got %d", p.options.DoubleClickDistance)
+	}
 	return nil
 }
 
+// postEvent queues window events for Update and schedules a redraw to
+// deliver them. It never blocks: the display thread that calls it is the one
+// that drains the queue, so an event that finds the queue full is dropped
+// unless its policy keeps it. Program.Send would wait for room forever.
+func (p *Program) postEvent(msgs ...Msg) {
+	queued := false
+	for _, msg := range msgs {
+		if p.queue.post(p.msgChan, msg) {
+			queued = true
+		}
+	}
+	if queued {
+		p.scheduleRedraw()
+	}
+}
+
 // scheduleRedraw marks that a redraw is needed and schedules it.
 func (p *Program) scheduleRedraw() {
+	if p.embedded {
+		p.scheduleHostRedraw()
+		return
+	}
 	if !p.needsRedraw && p.window != nil && p.widget != nil {
 		p.needsRedraw = true
 		p.window.UninhibitRedraw()
@@ -274,6 +829,17 @@ func (p *Program) scheduleRedraw() {
 
 // quit handles the quit process.
 func (p *Program) quit() {
+	// Quitting a secondary window only closes that window
+	if p.opener != nil {
+		p.requestClose()
+		return
+	}
+	// The host of an embedded program owns the display loop
+	if p.embedded {
+		p.cancel()
+		return
+	}
+
 	p.cancel()
 	if p.display != nil {
 		p.display.Exit()
@@ -283,21 +849,31 @@ func (p *Program) quit() {
 // Send sends a message to the program's Update function.
 // This is thread-safe and can be called from any goroutine.
 func (p *Program) Send(msg Msg) {
-	select {
-	case p.msgChan <- msg:
-	case <-p.ctx.Done():
-	}
+	p.queue.send(p.ctx, p.msgChan, msg)
+}
+
+// QueueStats returns the counters of the program's message queue.
+// This is thread-safe and can be called from any goroutine.
+func (p *Program) QueueStats() QueueStats {
+	return p.queue.stats(p.msgChan)
 }
 
-// Quit signals the program to exit gracefully.
+// Quit signals the program to exit gracefully. It can be called from any
+// goroutine and more than once.
 func (p *Program) Quit() {
-	p.cancel()
-	close(p.quitChan)
+	p.quitOnce.Do(func() {
+		close(p.quitChan)
+	})
 }
 
 // Resize implements window.WidgetHandler interface.
 // It handles window resize events and sends WindowSizeMsg.
 func (p *Program) Resize(widget *window.Widget, width int32, height int32, pwidth int32, pheight int32) {
+	// The host of an embedded program sets its area with SetBounds
+	if p.embedded {
+		return
+	}
+
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
@@ -305,29 +881,84 @@ func (p *Program) Resize(widget *window.Widget, width int32, height int32, pwidt
 	if width != pwidth || height != pheight {
 		widget.SetAllocation(0, 0, pwidth, pheight)
 	}
+	p.pixelWidth = pwidth
+	p.pixelHeight = pheight
+
+	p.layoutGrid(window.Rectangle{Width: pwidth, Height: pheight})
+}
+
+// layoutGrid fits the grid into the given area of the window, which is the
+// whole window unless the program is embedded, and sends WindowSizeMsg.
+// Must be called with p.mu held.
+func (p *Program) layoutGrid(bounds window.Rectangle) {
+	pwidth, pheight := bounds.Width, bounds.Height
 
 	// Calculate grid dimensions based on cell size
 	cellWidth := p.renderer.CellWidth()
 	cellHeight := p.renderer.CellHeight()
 
-	gridWidth := int(pwidth / cellWidth)
-	gridHeight := int(pheight / cellHeight)
+	l := computeLayout(pwidth, pheight, cellWidth, cellHeight, p.options.Padding, p.options.ContentPlacement)
+	gridWidth := l.cols
+	gridHeight := l.rows
+
+	// Keep the window within the size limits set with SetMinSize and
+	// SetMaxSize, and snapped to whole cells, unless the compositor
+	// controls its size
+	if !p.windowState.Fullscreen && !p.windowState.Maximized {
+		cols, rows := p.clampCells(gridWidth, gridHeight)
+		if (cols != gridWidth || rows != gridHeight) && p.resizeToCells(cols, rows) {
+			Debug("Window size %dx%d cells is outside the limits, resizing to %dx%d", gridWidth, gridHeight, cols, rows)
+			return
+		}
+		// Inline windows are sized in whole cells already, and snapping
+		// would undo the steps of their smooth resizing
+		if p.options.SnapToCells && !p.options.Inline && cols > 0 && rows > 0 {
+			snappedWidth, snappedHeight := windowSizeFor(cols, rows, cellWidth, cellHeight, p.options.Padding)
+			if (snappedWidth != pwidth || snappedHeight != pheight) && p.resizeToCells(cols, rows) {
+				Debug("Snapping window size %dx%d pixels to %dx%d", pwidth, pheight, snappedWidth, snappedHeight)
+				return
+			}
+		}
+	}
+	if p.maxCols > 0 && gridWidth > p.maxCols {
+		gridWidth = p.maxCols
+	}
+	if p.maxRows > 0 && gridHeight > p.maxRows {
+		gridHeight = p.maxRows
+	}
 
 	Debug("Window resized: %dx%d pixels -> %dx%d cells", pwidth, pheight, gridWidth, gridHeight)
 
 	// Store dimensions
 	p.windowWidth = gridWidth
 	p.windowHeight = gridHeight
+	p.originX = bounds.X + l.originX
+	p.originY = bounds.Y + l.originY
+	p.renderMu.Lock()
+	p.renderer.SetBounds(bounds.X, bounds.Y, bounds.Width, bounds.Height)
+	p.renderer.SetOrigin(p.originX, p.originY)
+	p.renderMu.Unlock()
+
+	// Inline windows follow the view, so report the room the view may
+	// grow into rather than the current size, like a terminal does for
+	// programs that do not use the alternate screen
+	sizeMsg := WindowSizeMsg{
+		Width:       gridWidth,
+		Height:      gridHeight,
+		PixelWidth:  int(pwidth),
+		PixelHeight: int(pheight),
+		CellWidth:   int(cellWidth),
+		CellHeight:  int(cellHeight),
+	}
+	if p.options.Inline && p.maxRows > 0 {
+		sizeMsg.Height = p.maxRows
+	}
+	if p.options.Inline && p.options.InlineWidth && p.maxCols > 0 {
+		sizeMsg.Width = p.maxCols
+	}
 
 	// Send WindowSizeMsg (non-blocking)
-	select {
-	case p.msgChan <- WindowSizeMsg{
-		Width:  gridWidth,
-		Height: gridHeight,
-	}:
-	default:
-		Warn("Message channel full, dropping WindowSizeMsg")
-	}
+	p.queue.post(p.msgChan, sizeMsg)
 	
 	// Schedule redraw on resize
 	p.scheduleRedraw()
@@ -338,6 +969,24 @@ func (p *Program) Resize(widget *window.Widget, width int32, height int32, pwidt
 func (p *Program) Redraw(widget *window.Widget) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
+
+	// Quit and Kill wake the display thread where the backend supports
+	// it; otherwise they take effect on the next frame
+	select {
+	case <-p.quitChan:
+		p.quit()
+		return
+	default:
+	}
+	wakeups := p.wakeups
+	p.wakeups = nil
+	for _, fn := range wakeups {
+		fn()
+	}
+
+	if p.embedded {
+		p.hostWidget = widget
+	}
 	
 	// Clear the needsRedraw flag
 	p.needsRedraw = false
@@ -350,76 +999,44 @@ func (p *Program) Redraw(widget *window.Widget) {
 			X:      p.pendingMotionX,
 			Y:      p.pendingMotionY,
 			Type:   MouseMotion,
-			Button: MouseButtonNone,
+			Button: p.heldButton,
 		}
 		p.motionPending = false
 		processedMotion = true
 		
 		// Process the motion message directly
-		func() {
-			defer func() {
-				if r := recover(); r != nil {
-					Error("Panic in Update(): %v", r)
-					Error("Stack trace: %v", getStackTrace())
-					p.quit()
-				}
-			}()
-			
-			var cmd Cmd
-			p.model, cmd = p.model.Update(*mouseMsg)
-			if cmd != nil {
-				p.cmdExec.Execute(cmd)
-			}
-		}()
+		p.update(p.filter(p.withZones(*mouseMsg)))
 	}
 
-	// Process pending messages (non-blocking loop)
+	// Advance kinetic scrolling and deliver the resulting wheel events
+	processedKinetic := p.processKineticScroll()
+
+	// Process pending messages (non-blocking)
 	hadMessages := false
-	var messagesToProcess []Msg
-	
-	// Collect all pending messages
-	for {
-		select {
-		case msg := <-p.msgChan:
-			hadMessages = true
-			
-			// Check if this is a quit message
-			if _, isQuit := msg.(quitMsg); isQuit {
-				p.quit()
-				return
-			}
-			
-			messagesToProcess = append(messagesToProcess, msg)
-		default:
-			// No more messages to process
-			goto done
+	for _, msg := range p.queue.drain(p.msgChan) {
+		hadMessages = true
+
+		// Messages addressed to the Program itself never reach Update
+		if p.handleProgramMsg(msg) {
+			continue
 		}
-	}
-done:
-	
-	// Process all collected messages
-	for _, msg := range messagesToProcess {
-		// Call Update with panic recovery
-		func() {
-			defer func() {
-				if r := recover(); r != nil {
-					Error("Panic in Update(): %v", r)
-					Error("Stack trace: %v", getStackTrace())
-					// Exit gracefully on panic
-					p.quit()
-				}
-			}()
-			
-			var cmd Cmd
-			p.model, cmd = p.model.Update(msg)
-
-			// Execute the returned command
-			if cmd != nil {
-				p.cmdExec.Execute(cmd)
-			}
-		}()
-	}
 
+		// Filters may replace or drop any other message, and veto
+		// quitting. They run right before the message's update, so they
+		// see the model as the messages before it left it.
+		msg = p.filter(p.withZones(msg))
+		if msg == nil {
+			continue
+		}
+
+		// Check if this is a quit message
+		if _, isQuit := msg.(QuitMsg); isQuit {
+			p.quit()
+			return
+		}
+
+		p.update(msg)
+	}
 
 	// Get the current view with panic recovery
 	var view string
@@ -427,18 +1044,27 @@ done:
 		defer func() {
 			if r := recover(); r != nil {
 				Error("Panic in View(): %v", r)
-				Error("Stack trace: %v", getStackTrace())
+				stack := getStackTrace()
+				Error("Stack trace: %v", stack)
+				p.recordPanic(r, stack)
 				// Use empty view on panic
 				view = ""
 				// Exit gracefully on panic
 				p.quit()
 			}
 		}()
-		view = p.model.View()
+		view = p.viewFn(p.model)
 	}()
 
-	// Skip rendering if view hasn't changed (unless we processed messages)
-	if view == p.lastView && p.lastView != "" && !hadMessages && !processedMotion {
+	// Follow the size of the view in inline mode
+	if p.options.Inline {
+		p.fitToContent(view)
+	}
+
+	// Skip rendering if view hasn't changed (unless we processed messages).
+	// The host of an embedded program may have drawn over it, so it
+	// always renders when asked.
+	if view == p.lastView && p.lastView != "" && !hadMessages && !processedMotion && !processedKinetic && !p.selectionChanged && !p.embedded {
 		return
 	}
 
@@ -455,9 +1081,15 @@ done:
 		Error("ParseANSI returned nil grid, skipping render")
 		return
 	}
+	p.gridMu.Lock()
+	p.grid = grid
+	p.gridMu.Unlock()
+	p.selection.highlight(grid, p.options.Theme)
 
 	// Render the grid
+	p.renderMu.Lock()
 	err := p.renderer.Render(grid, surface)
+	p.renderMu.Unlock()
 	if err != nil {
 		// Log error but continue - don't crash the application
 		Error("Render failed: %v", err)
@@ -468,7 +1100,8 @@ done:
 
 	// Update state
 	p.lastView = view
-	p.lastRender = time.Now()
+	p.lastRender = p.options.Clock.Now()
+	p.selectionChanged = false
 
 	// Uninhibit redraw to allow future redraws
 	if p.window != nil {
@@ -480,10 +1113,152 @@ done:
 		if processedMotion && p.motionPending {
 			p.scheduleRedraw()
 		}
+
+		// Keep frames coming while kinetic scrolling is in progress
+		if p.kinetic.active {
+			p.scheduleRedraw()
+		}
+	}
+}
+
+
+
+// processKineticScroll advances kinetic scrolling and passes the resulting
+// wheel events directly to Update. It reports whether any were delivered.
+// Must be called with p.mu held.
+func (p *Program) processKineticScroll() bool {
+	if !p.kinetic.active {
+		return false
+	}
+	if !p.mouseMode.reportsButtons() {
+		p.kinetic.stop()
+		return false
+	}
+
+	processed := false
+	deltas := p.kinetic.step(p.options.Clock.Now())
+	for axis, delta := range deltas {
+		if delta == 0 {
+			continue
+		}
+		mouseMsg := p.scrollMsg(p.pointerX, p.pointerY, uint32(axis), delta)
+		if mouseMsg == nil {
+			continue
+		}
+		processed = true
+
+		p.update(p.filter(p.withZones(*mouseMsg)))
+	}
+	return processed
+}
+
+// scrollMsg accumulates an axis value and returns a wheel MouseMsg at the
+// given pixel position for the whole steps it completes, or nil if no step
+// was completed. Must be called with p.mu held.
+func (p *Program) scrollMsg(x, y float32, axis uint32, value float32) *MouseMsg {
+	cellWidth := p.renderer.CellWidth()
+	cellHeight := p.renderer.CellHeight()
+
+	unitsPerLine := p.options.ScrollUnitsPerLine
+	if unitsPerLine == 0 {
+		if axis == axisHorizontal {
+			unitsPerLine = float32(cellWidth)
+		} else {
+			unitsPerLine = float32(cellHeight)
+		}
+	}
+
+	steps := p.scroll.add(axis, value, unitsPerLine)
+	if steps == 0 {
+		return nil
+	}
+
+	x, y = p.gridPoint(x, y)
+	mouseMsg := MapMouseScroll(x, y, axis, float32(steps), cellWidth, cellHeight)
+	if mouseMsg != nil {
+		mouseMsg.Steps = absInt(steps)
+	}
+	return mouseMsg
+}
+
+// cellAt converts a pixel position in the window to a cell position.
+func (p *Program) cellAt(x, y float32) (int, int) {
+	x, y = p.gridPoint(x, y)
+	return int(x / float32(p.renderer.CellWidth())), int(y / float32(p.renderer.CellHeight()))
+}
+
+// gridPoint converts a pixel position in the window to a pixel position
+// relative to the top-left cell. Positions in the padding or leftover space
+// are moved to the nearest cell, so events there are reported at the edge
+// of the grid.
+func (p *Program) gridPoint(x, y float32) (float32, float32) {
+	x -= float32(p.originX)
+	y -= float32(p.originY)
+	x = max(x, 0)
+	y = max(y, 0)
+	if p.windowWidth > 0 {
+		x = min(x, float32(int32(p.windowWidth)*p.renderer.CellWidth()-1))
+	}
+	if p.windowHeight > 0 {
+		y = min(y, float32(int32(p.windowHeight)*p.renderer.CellHeight()-1))
+	}
+	return x, y
+}
+
+// withZones fills in the zones under the pointer for a MouseMsg, based on
+// the last rendered frame. Other messages are returned unchanged. Must be
+// called with p.mu held.
+func (p *Program) withZones(msg Msg) Msg {
+	mouseMsg, ok := msg.(MouseMsg)
+	if !ok || p.grid == nil || len(p.grid.Zones) == 0 {
+		return msg
 	}
+	mouseMsg.Zones = p.grid.ZoneAt(mouseMsg.X, mouseMsg.Y)
+	return mouseMsg
 }
 
+// ZoneAt returns the IDs of the zones marked with Mark that cover the given
+// cell in the last rendered frame, innermost first. It is safe to call from
+// Update and from other goroutines.
+func (p *Program) ZoneAt(x, y int) []string {
+	p.gridMu.Lock()
+	defer p.gridMu.Unlock()
+
+	if p.grid == nil {
+		return nil
+	}
+	return p.grid.ZoneAt(x, y)
+}
 
+// handleProgramMsg applies internal messages that configure the Program.
+// It reports whether the message was consumed. Must be called with p.mu held.
+func (p *Program) handleProgramMsg(msg Msg) bool {
+	switch m := msg.(type) {
+	case KeyMsg:
+		// The screenshot key is taken by the Program when it is enabled
+		if m.Type == KeyPrintScreen && p.options.ScreenshotDir != "" {
+			p.saveScreenshot(filepath.Join(p.options.ScreenshotDir, screenshotName(p.options.Clock.Now())))
+			return true
+		}
+	case screenshotMsg:
+		p.saveScreenshot(m.path)
+		return true
+	case setMouseModeMsg:
+		Debug("Mouse mode changed: %v -> %v", p.mouseMode, m.mode)
+		p.mouseMode = m.mode
+		p.motionPending = false
+		return true
+	case setPointerShapeMsg:
+		Debug("Pointer shape changed: %v -> %v", p.pointerShape, m.shape)
+		p.pointerShape = m.shape
+		p.showPointerShape()
+		return true
+	case dropFinishedMsg:
+		m.finish()
+		return true
+	}
+	return p.handleWindowMsg(msg)
+}
 
 // Key implements window.KeyboardHandler interface.
 // It handles keyboard input events.
@@ -512,20 +1287,37 @@ func (p *Program) Key(
 	keyMsg := MapKeyboardEvent(input, keysym, key, input.GetModifiers(), state)
 	if keyMsg != nil {
 		Debug("Keyboard event: key=%d, keysym=%d, state=%d", key, keysym, state)
-		// Send to channel (non-blocking)
-		select {
-		case p.msgChan <- *keyMsg:
-			// Schedule a redraw to process the message
-			p.scheduleRedraw()
-		default:
-			Warn("Message channel full, dropping keyboard event")
-		}
+		p.postEvent(*keyMsg)
 	}
 }
 
 // Focus implements window.KeyboardHandler interface.
+// It sends FocusMsg when the window gains keyboard focus and BlurMsg when it
+// loses it (device is nil), if focus reporting is enabled.
 func (p *Program) Focus(win *window.Window, device *window.Input) {
-	// Send focus event (could be extended to send a FocusMsg)
+	focused := device != nil
+
+	p.mu.Lock()
+	changed := focused != p.focused
+	p.focused = focused
+	report := p.options.ReportFocus
+
+	// Windows with a toplevel take the activated state from the compositor
+	if p.toplevel == nil {
+		p.setWindowState(func(s *WindowStateMsg) { s.Activated = focused })
+	}
+	p.mu.Unlock()
+
+	if !changed || !report {
+		return
+	}
+
+	Debug("Keyboard focus changed: focused=%v", focused)
+	if focused {
+		p.postEvent(FocusMsg{})
+	} else {
+		p.postEvent(BlurMsg{})
+	}
 }
 
 // Enter implements window.WidgetHandler interface for pointer enter events.
@@ -533,11 +1325,40 @@ func (p *Program) Enter(widget *window.Widget, input *window.Input, x float32, y
 	// Store pointer position
 	p.pointerX = x
 	p.pointerY = y
+
+	p.pointerEntered(x, y)
 }
 
 // Leave implements window.WidgetHandler interface for pointer leave events.
+// It sends MouseLeaveMsg unless mouse reporting is disabled.
 func (p *Program) Leave(widget *window.Widget, input *window.Input) {
-	// Mouse left the window
+	p.mu.Lock()
+	wasInside := p.pointerInside
+	p.pointerInside = false
+	p.cellPosValid = false
+	report := p.mouseMode.reportsButtons()
+	p.mu.Unlock()
+
+	if wasInside && report {
+		p.postEvent(MouseLeaveMsg{})
+	}
+}
+
+// pointerEntered sends MouseEnterMsg the first time the pointer is seen
+// inside the window after it was outside, unless mouse reporting is disabled.
+func (p *Program) pointerEntered(x, y float32) {
+	p.mu.Lock()
+	wasInside := p.pointerInside
+	p.pointerInside = true
+	report := p.mouseMode.reportsButtons()
+	p.mu.Unlock()
+
+	if wasInside || !report {
+		return
+	}
+
+	cellX, cellY := p.cellAt(x, y)
+	p.postEvent(MouseEnterMsg{X: cellX, Y: cellY})
 }
 
 // Motion implements window.WidgetHandler interface for pointer motion events.
@@ -546,12 +1367,34 @@ func (p *Program) Motion(widget *window.Widget, input *window.Input, time uint32
 	p.pointerX = x
 	p.pointerY = y
 
-	cellWidth := p.renderer.CellWidth()
-	cellHeight := p.renderer.CellHeight()
+	// Store input reference
+	if p.input == nil {
+		p.input = input
+	}
+
+	// Not every backend reports pointer enter, so the first motion counts
+	p.pointerEntered(x, y)
 
 	// Calculate cell position
-	cellX := int(x / float32(cellWidth))
-	cellY := int(y / float32(cellHeight))
+	cellX, cellY := p.cellAt(x, y)
+
+	// A selection being dragged takes the motion instead of the model
+	p.mu.Lock()
+	if p.selection.dragging {
+		if p.selection.extend(cellX, cellY) {
+			p.selectionChanged = true
+			p.scheduleRedraw()
+		}
+		cursor := p.pointerCursor(PointerText, true)
+		p.mu.Unlock()
+		return cursor
+	}
+	reportMotion := p.mouseMode.reportsMotion(p.heldButton != MouseButtonNone)
+	cursor := p.pointerCursor(p.pointerShapeAt(cellX, cellY), true)
+	p.mu.Unlock()
+	if !reportMotion {
+		return cursor
+	}
 
 	// Only mark motion as pending if the cell position has changed
 	if !p.cellPosValid || cellX != p.lastCellX || cellY != p.lastCellY {
@@ -575,7 +1418,7 @@ func (p *Program) Motion(widget *window.Widget, input *window.Input, time uint32
 		}
 	}
 
-	return window.CursorLeftPtr
+	return cursor
 }
 
 // Button implements window.WidgetHandler interface for pointer button events.
@@ -592,75 +1435,360 @@ func (p *Program) Button(
 
 	Debug("Mouse button: button=%d, state=%d", button, state)
 
+	// Store input reference
+	if p.input == nil {
+		p.input = input
+	}
+
 	// Use stored pointer position
-	mouseMsg := MapMouseButton(p.pointerX, p.pointerY, button, state, cellWidth, cellHeight)
+	gridX, gridY := p.gridPoint(p.pointerX, p.pointerY)
+	mouseMsg := MapMouseButton(gridX, gridY, button, state, cellWidth, cellHeight)
+	shift := input != nil && input.GetModifiers()&window.ModShiftMask != 0
+
+	p.mu.Lock()
+	if mouseMsg != nil && p.handleSelectionButton(*mouseMsg, shift, time) {
+		p.mu.Unlock()
+		return
+	}
+
+	// Track the held button so drags can be reported in cell motion mode,
+	// and count consecutive clicks for double and triple click detection
 	if mouseMsg != nil {
-		p.Send(*mouseMsg)
-		// Schedule a redraw to process the message
+		if mouseMsg.Type == MousePress {
+			p.heldButton = mouseMsg.Button
+			mouseMsg.ClickCount = p.clicks.press(mouseMsg.X, mouseMsg.Y, mouseMsg.Button, time)
+		} else {
+			if mouseMsg.Button == p.heldButton {
+				p.heldButton = MouseButtonNone
+			}
+			mouseMsg.ClickCount = p.clicks.release(mouseMsg.Button)
+		}
+	}
+	reportButtons := p.mouseMode.reportsButtons()
+	p.mu.Unlock()
+
+	if mouseMsg != nil && reportButtons {
+		p.postEvent(*mouseMsg)
+	}
+}
+
+// handleSelectionButton starts, finishes or clears the built-in text
+// selection for a left button event. It reports whether the event was used
+// by the selection and must not be passed to the model. Must be called with
+// p.mu held.
+func (p *Program) handleSelectionButton(mouseMsg MouseMsg, shift bool, eventTime uint32) bool {
+	if !p.options.MouseSelection || mouseMsg.Button != MouseButtonLeft {
+		return false
+	}
+
+	if mouseMsg.Type == MouseRelease {
+		if !p.selection.dragging {
+			return false
+		}
+		if p.selection.finish() {
+			p.copyToClipboard(p.selection.text(p.grid))
+		}
+		return true
+	}
+
+	// Any other click clears the selection, like in a terminal
+	if p.selection.clear() {
+		p.selectionChanged = true
+		p.scheduleRedraw()
+	}
+	if !shift && p.mouseMode.reportsButtons() {
+		return false
+	}
+
+	unit := selectCells
+	switch clicks := p.clicks.press(mouseMsg.X, mouseMsg.Y, mouseMsg.Button, eventTime); {
+	case clicks == 2:
+		unit = selectWords
+	case clicks >= 3:
+		unit = selectLines
+	}
+	p.selection.start(mouseMsg.X, mouseMsg.Y, unit)
+	if p.selection.active {
+		p.selectionChanged = true
 		p.scheduleRedraw()
 	}
+	return true
 }
 
 // Axis implements window.WidgetHandler interface for pointer axis (scroll) events.
+// Discrete wheel steps and sub-line touchpad motion are accumulated into
+// whole-line steps before a MouseMsg is sent.
 func (p *Program) Axis(widget *window.Widget, input *window.Input, time uint32, axis uint32, value float32) {
-	cellWidth := p.renderer.CellWidth()
-	cellHeight := p.renderer.CellHeight()
-
+	p.mu.Lock()
+	if !p.mouseMode.reportsButtons() {
+		p.mu.Unlock()
+		return
+	}
+	if p.options.KineticScroll && p.axisSource == wl.PointerAxisSourceFinger {
+		p.kinetic.track(axis, value, time)
+	} else {
+		p.kinetic.stop()
+	}
 	// Use stored pointer position
-	mouseMsg := MapMouseScroll(p.pointerX, p.pointerY, axis, value, cellWidth, cellHeight)
+	mouseMsg := p.scrollMsg(p.pointerX, p.pointerY, axis, value)
+	p.mu.Unlock()
+
 	if mouseMsg != nil {
-		p.Send(*mouseMsg)
-		// Schedule a redraw to process the message
-		p.scheduleRedraw()
+		p.postEvent(*mouseMsg)
 	}
 }
 
 // TouchUp implements window.WidgetHandler interface.
+// It sends a TouchMsg and finishes any emulated click or recognized gesture.
 func (p *Program) TouchUp(widget *window.Widget, input *window.Input, serial uint32, time uint32, id int32) {
-	// Touch events not implemented yet
+	p.mu.Lock()
+	pt, ok := p.touchPoints[id]
+	if !ok {
+		p.mu.Unlock()
+		return
+	}
+	delete(p.touchPoints, id)
+
+	cellX, cellY := p.cellAt(pt.x, pt.y)
+	msgs := []Msg{TouchMsg{ID: int(id), X: cellX, Y: cellY, Type: TouchUp}}
+
+	if p.touchEmulating && id == p.touchPrimary {
+		msgs = append(msgs, p.endTouchPress(pt)...)
+	}
+	if p.touchScrolling && (id == p.touchScrollIDs[0] || id == p.touchScrollIDs[1]) {
+		p.touchScrolling = false
+		p.scroll.reset(axisVertical)
+		p.scroll.reset(axisHorizontal)
+	}
+	if p.options.Gestures && p.gestures != nil {
+		if gesture := p.gestures.up(id, time); gesture != nil {
+			msgs = append(msgs, *gesture)
+		}
+	}
+	p.mu.Unlock()
+
+	p.sendTouchMsgs(msgs)
 }
 
 // TouchDown implements window.WidgetHandler interface.
+// It sends a TouchMsg and, when enabled, starts an emulated click or scroll
+// and feeds the gesture recognizer.
 func (p *Program) TouchDown(
 	widget *window.Widget,
 	input *window.Input,
 	serial uint32,
-	time uint32,
+	eventTime uint32,
 	id int32,
 	x float32,
 	y float32,
 ) {
-	// Touch events not implemented yet
+	p.mu.Lock()
+	if p.touchPoints == nil {
+		p.touchPoints = make(map[int32]touchPoint)
+	}
+	pt := touchPoint{x: x, y: y}
+	p.touchPoints[id] = pt
+
+	cellX, cellY := p.cellAt(x, y)
+	msgs := []Msg{TouchMsg{ID: int(id), X: cellX, Y: cellY, Type: TouchDown}}
+
+	if p.options.TouchMouseEmulation && p.mouseMode.reportsButtons() {
+		switch len(p.touchPoints) {
+		case 1:
+			// A single finger presses the left button
+			p.touchPrimary = id
+			p.touchEmulating = true
+			p.heldButton = MouseButtonLeft
+			msgs = append(msgs, MouseMsg{
+				X:          cellX,
+				Y:          cellY,
+				Type:       MousePress,
+				Button:     MouseButtonLeft,
+				ClickCount: p.clicks.press(cellX, cellY, MouseButtonLeft, eventTime),
+			})
+		case 2:
+			// A second finger turns the touch into a two-finger scroll
+			if p.touchEmulating {
+				msgs = append(msgs, p.endTouchPress(p.touchPoints[p.touchPrimary])...)
+			}
+			p.touchScrolling = true
+			p.touchScrollIDs = [2]int32{p.touchPrimary, id}
+			p.touchScrollCenter = centroid(p.touchPoints, p.touchScrollIDs[:])
+		}
+	}
+
+	longPressSeq := 0
+	if p.options.Gestures {
+		if p.gestures == nil {
+			p.gestures = newGestureRecognizer(float32(p.renderer.CellWidth()), float32(p.renderer.CellHeight()))
+		}
+		gridX, gridY := p.gridPoint(x, y)
+		seq := p.gestures.down(id, gridX, gridY, eventTime)
+		if len(p.touchPoints) == 1 {
+			longPressSeq = seq
+		}
+	}
+	p.mu.Unlock()
+
+	p.sendTouchMsgs(msgs)
+
+	if longPressSeq != 0 {
+		go p.waitLongPress(longPressSeq)
+	}
+}
+
+// waitLongPress sends a long-press gesture if the finger of the gesture
+// with the given sequence number is still held in place after the delay.
+func (p *Program) waitLongPress(seq int) {
+	timer := p.options.Clock.NewTimer(longPressDelay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C():
+	case <-p.ctx.Done():
+		return
+	}
+
+	p.mu.Lock()
+	gesture := p.gestures.longPress(seq)
+	p.mu.Unlock()
+	if gesture != nil {
+		p.Send(*gesture)
+	}
 }
 
 // TouchMotion implements window.WidgetHandler interface.
+// It sends a TouchMsg when the finger moves to another cell and, when
+// enabled, drags the emulated mouse or scrolls with two fingers.
 func (p *Program) TouchMotion(widget *window.Widget, input *window.Input, time uint32, id int32, x float32, y float32) {
-	// Touch events not implemented yet
+	p.mu.Lock()
+	prev, ok := p.touchPoints[id]
+	if !ok {
+		p.mu.Unlock()
+		return
+	}
+	pt := touchPoint{x: x, y: y}
+	p.touchPoints[id] = pt
+
+	var msgs []Msg
+	prevX, prevY := p.cellAt(prev.x, prev.y)
+	cellX, cellY := p.cellAt(x, y)
+	cellChanged := cellX != prevX || cellY != prevY
+	if cellChanged {
+		msgs = append(msgs, TouchMsg{ID: int(id), X: cellX, Y: cellY, Type: TouchMotion})
+	}
+
+	if p.touchEmulating && id == p.touchPrimary && cellChanged && p.mouseMode.reportsMotion(true) {
+		msgs = append(msgs, MouseMsg{X: cellX, Y: cellY, Type: MouseMotion, Button: MouseButtonLeft})
+	}
+
+	if p.touchScrolling && (id == p.touchScrollIDs[0] || id == p.touchScrollIDs[1]) {
+		center := centroid(p.touchPoints, p.touchScrollIDs[:])
+		dx := center.x - p.touchScrollCenter.x
+		dy := center.y - p.touchScrollCenter.y
+		p.touchScrollCenter = center
+
+		// Content follows the fingers: moving them up scrolls down
+		if mouseMsg := p.scrollMsg(center.x, center.y, axisVertical, -dy); mouseMsg != nil {
+			msgs = append(msgs, *mouseMsg)
+		}
+		if mouseMsg := p.scrollMsg(center.x, center.y, axisHorizontal, -dx); mouseMsg != nil {
+			msgs = append(msgs, *mouseMsg)
+		}
+	}
+
+	if p.options.Gestures && p.gestures != nil {
+		gridX, gridY := p.gridPoint(x, y)
+		p.gestures.motion(id, gridX, gridY)
+	}
+	p.mu.Unlock()
+
+	p.sendTouchMsgs(msgs)
 }
 
 // TouchFrame implements window.WidgetHandler interface.
 func (p *Program) TouchFrame(widget *window.Widget, input *window.Input) {
-	// Touch events not implemented yet
+	// Touch events are handled as they arrive
 }
 
 // TouchCancel implements window.WidgetHandler interface.
+// It cancels all touch points, emulated clicks and gestures in progress.
 func (p *Program) TouchCancel(widget *window.Widget, width int32, height int32) {
-	// Touch events not implemented yet
+	p.mu.Lock()
+	var msgs []Msg
+	for id, pt := range p.touchPoints {
+		cellX, cellY := p.cellAt(pt.x, pt.y)
+		msgs = append(msgs, TouchMsg{ID: int(id), X: cellX, Y: cellY, Type: TouchCancel})
+		if p.touchEmulating && id == p.touchPrimary {
+			msgs = append(msgs, p.endTouchPress(pt)...)
+		}
+	}
+	p.touchPoints = nil
+	p.touchScrolling = false
+	if p.gestures != nil {
+		p.gestures.cancel()
+	}
+	p.mu.Unlock()
+
+	p.sendTouchMsgs(msgs)
+}
+
+// endTouchPress releases the emulated left button at the given position.
+// Must be called with p.mu held.
+func (p *Program) endTouchPress(pt touchPoint) []Msg {
+	p.touchEmulating = false
+	if p.heldButton == MouseButtonLeft {
+		p.heldButton = MouseButtonNone
+	}
+	cellX, cellY := p.cellAt(pt.x, pt.y)
+	return []Msg{MouseMsg{
+		X:          cellX,
+		Y:          cellY,
+		Type:       MouseRelease,
+		Button:     MouseButtonLeft,
+		ClickCount: p.clicks.release(MouseButtonLeft),
+	}}
+}
+
+// sendTouchMsgs sends messages produced by touch input and schedules a
+// redraw to process them.
+func (p *Program) sendTouchMsgs(msgs []Msg) {
+	if len(msgs) == 0 {
+		return
+	}
+	p.postEvent(msgs...)
 }
 
 // AxisSource implements window.WidgetHandler interface.
+// It records whether the following axis events come from a wheel or fingers.
 func (p *Program) AxisSource(widget *window.Widget, input *window.Input, source uint32) {
-	// Axis source events not needed for basic functionality
+	p.mu.Lock()
+	p.axisSource = source
+	p.mu.Unlock()
 }
 
 // AxisStop implements window.WidgetHandler interface.
-func (p *Program) AxisStop(widget *window.Widget, input *window.Input, time uint32, axis uint32) {
-	// Axis stop events not needed for basic functionality
+// It discards partial steps and, with kinetic scrolling enabled, starts a
+// fling when the fingers leave the touchpad.
+func (p *Program) AxisStop(widget *window.Widget, input *window.Input, eventTime uint32, axis uint32) {
+	p.mu.Lock()
+	p.scroll.reset(axis)
+	fling := false
+	if p.options.KineticScroll && p.axisSource == wl.PointerAxisSourceFinger {
+		fling = p.kinetic.fling(axis, p.options.Clock.Now())
+	}
+	if fling {
+		p.scheduleRedraw()
+	}
+	p.mu.Unlock()
 }
 
 // AxisDiscrete implements window.WidgetHandler interface.
+// Discrete wheel notches take precedence over the axis value that follows.
 func (p *Program) AxisDiscrete(widget *window.Widget, input *window.Input, axis uint32, discrete int32) {
-	// Axis discrete events not needed for basic functionality
+	p.mu.Lock()
+	p.scroll.addDiscrete(axis, discrete)
+	p.mu.Unlock()
 }
 
 // PointerFrame implements window.WidgetHandler interface.
@@ -0,0 +1,223 @@
+package lib
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// MessagePolicy specifies what happens to a message when the message queue
+// of the program is full.
+type MessagePolicy int
+
+const (
+	// PolicyWait makes commands and Send wait for room in the queue. Window
+	// events that find the queue full are dropped. This is the default.
+	PolicyWait MessagePolicy = iota
+
+	// PolicyDrop drops the message when the queue is full.
+	PolicyDrop
+
+	// PolicyKeepLatest keeps only the most recent message of the type once
+	// the queue is full; a newer message replaces it. Update receives it
+	// after the messages that were waiting when the queue filled up.
+	PolicyKeepLatest
+
+	// PolicyNeverDrop queues the message beyond the queue size instead of
+	// dropping it or waiting.
+	PolicyNeverDrop
+)
+
+// String returns a string representation of the policy.
+func (m MessagePolicy) String() string {
+	switch m {
+	case PolicyWait:
+		return "wait"
+	case PolicyDrop:
+		return "drop"
+	case PolicyKeepLatest:
+		return "keep-latest"
+	case PolicyNeverDrop:
+		return "never-drop"
+	default:
+		return "unknown"
+	}
+}
+
+// defaultMessagePolicies returns the policies used unless changed with
+// WithMessagePolicy: key input and closed windows are never dropped, and
+// only the latest window size is delivered.
+func defaultMessagePolicies() map[reflect.Type]MessagePolicy {
+	return map[reflect.Type]MessagePolicy{
+		reflect.TypeOf(KeyMsg{}):          PolicyNeverDrop,
+		reflect.TypeOf(WindowClosedMsg{}): PolicyNeverDrop,
+		reflect.TypeOf(WindowSizeMsg{}):   PolicyKeepLatest,
+	}
+}
+
+// QueueStats holds the counters of the message queue of a program.
+type QueueStats struct {
+	// Queued is the number of messages waiting for Update.
+	Queued int
+
+	// Enqueued is the number of messages queued since the program started.
+	Enqueued uint64
+
+	// Dropped is the number of messages dropped because the queue was full.
+	Dropped uint64
+
+	// Coalesced is the number of messages replaced by a newer message of
+	// the same type before Update received them.
+	Coalesced uint64
+}
+
+// messageQueue applies the message policies on top of the message channel
+// of a program. Messages that must not wait or be dropped, and the latest
+// messages of coalesced types, are kept here until Redraw drains them. The
+// channel is passed to every method, so a program's channel can be replaced
+// before it runs.
+type messageQueue struct {
+	policies map[reflect.Type]MessagePolicy
+
+	mu       sync.Mutex
+	latest   map[reflect.Type]Msg
+	order    []reflect.Type
+	overflow []Msg
+
+	enqueued  atomic.Uint64
+	dropped   atomic.Uint64
+	coalesced atomic.Uint64
+}
+
+// newMessageQueue creates a queue with the given policies. A nil map uses
+// PolicyWait for every message.
+func newMessageQueue(policies map[reflect.Type]MessagePolicy) *messageQueue {
+	return &messageQueue{
+		policies: policies,
+		latest:   make(map[reflect.Type]Msg),
+	}
+}
+
+// policy returns the policy for the type of msg.
+func (q *messageQueue) policy(msg Msg) MessagePolicy {
+	return q.policies[reflect.TypeOf(msg)]
+}
+
+// post queues msg without blocking and reports whether it was queued.
+func (q *messageQueue) post(ch chan Msg, msg Msg) bool {
+	switch q.policy(msg) {
+	case PolicyKeepLatest:
+		t := reflect.TypeOf(msg)
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		// The message goes in the channel while it has room, unless an
+		// older message of the type or an overflowing message is waiting,
+		// which it must not overtake
+		_, waiting := q.latest[t]
+		if !waiting && len(q.overflow) == 0 {
+			select {
+			case ch <- msg:
+				q.enqueued.Add(1)
+				return true
+			default:
+			}
+		}
+		if waiting {
+			q.coalesced.Add(1)
+		} else {
+			q.order = append(q.order, t)
+		}
+		q.latest[t] = msg
+		q.enqueued.Add(1)
+		return true
+
+	case PolicyNeverDrop:
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		// Once messages overflow, later ones follow them to keep the order
+		if len(q.overflow) == 0 {
+			select {
+			case ch <- msg:
+				q.enqueued.Add(1)
+				return true
+			default:
+			}
+		}
+		q.overflow = append(q.overflow, msg)
+		q.enqueued.Add(1)
+		return true
+
+	default:
+		select {
+		case ch <- msg:
+			q.enqueued.Add(1)
+			return true
+		default:
+			q.dropped.Add(1)
+			Warn("Message queue full, dropping %T", msg)
+			return false
+		}
+	}
+}
+
+// send queues msg, waiting for room in the channel until ctx is done if its
+// policy is PolicyWait, and reports whether it was queued.
+func (q *messageQueue) send(ctx context.Context, ch chan Msg, msg Msg) bool {
+	if q.policy(msg) != PolicyWait {
+		return q.post(ch, msg)
+	}
+
+	select {
+	case ch <- msg:
+		q.enqueued.Add(1)
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// drain returns the messages waiting for Update: the channel first, then
+// the overflowing messages, then the latest messages of coalesced types.
+func (q *messageQueue) drain(ch chan Msg) []Msg {
+	var msgs []Msg
+collect:
+	for {
+		select {
+		case msg := <-ch:
+			msgs = append(msgs, msg)
+		default:
+			break collect
+		}
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	msgs = append(msgs, q.overflow...)
+	q.overflow = nil
+	for _, t := range q.order {
+		msgs = append(msgs, q.latest[t])
+		delete(q.latest, t)
+	}
+	q.order = q.order[:0]
+	return msgs
+}
+
+// pending returns the number of messages waiting for Update.
+func (q *messageQueue) pending(ch chan Msg) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return len(ch) + len(q.overflow) + len(q.order)
+}
+
+// stats returns the counters of the queue.
+func (q *messageQueue) stats(ch chan Msg) QueueStats {
+	return QueueStats{
+		Queued:    q.pending(ch),
+		Enqueued:  q.enqueued.Load(),
+		Dropped:   q.dropped.Load(),
+		Coalesced: q.coalesced.Load(),
+	}
+}
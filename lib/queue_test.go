@@ -0,0 +1,220 @@
+package lib
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestMessageQueue_Drop tests that PolicyWait and PolicyDrop messages posted
+// to a full queue are dropped and counted.
+func TestMessageQueue_Drop(t *testing.T) {
+	ch := make(chan Msg, 1)
+	q := newMessageQueue(map[reflect.Type]MessagePolicy{
+		reflect.TypeOf(MouseMsg{}): PolicyDrop,
+	})
+
+	if !q.post(ch, FocusMsg{}) {
+		t.Fatal("Expected first message to be queued")
+	}
+	if q.post(ch, BlurMsg{}) {
+		t.Error("Expected PolicyWait message to be dropped from a full queue")
+	}
+	if q.post(ch, MouseMsg{X: 1}) {
+		t.Error("Expected PolicyDrop message to be dropped from a full queue")
+	}
+
+	stats := q.stats(ch)
+	want := QueueStats{Queued: 1, Enqueued: 1, Dropped: 2}
+	if stats != want {
+		t.Errorf("stats = %+v, want %+v", stats, want)
+	}
+}
+
+// TestMessageQueue_NeverDrop tests that PolicyNeverDrop messages overflow
+// the channel and keep their order.
+func TestMessageQueue_NeverDrop(t *testing.T) {
+	ch := make(chan Msg, 1)
+	q := newMessageQueue(defaultMessagePolicies())
+
+	keys := []KeyMsg{
+		{Type: KeyRunes, Runes: []rune("a")},
+		{Type: KeyRunes, Runes: []rune("b")},
+		{Type: KeyRunes, Runes: []rune("c")},
+	}
+	for _, k := range keys {
+		if !q.post(ch, k) {
+			t.Fatalf("Expected %v to be queued", k)
+		}
+	}
+	if got := q.pending(ch); got != 3 {
+		t.Errorf("pending = %d, want 3", got)
+	}
+
+	msgs := q.drain(ch)
+	if len(msgs) != len(keys) {
+		t.Fatalf("Expected %d messages, got %d", len(keys), len(msgs))
+	}
+	for i, msg := range msgs {
+		if !reflect.DeepEqual(msg, keys[i]) {
+			t.Errorf("message %d = %v, want %v", i, msg, keys[i])
+		}
+	}
+	if got := q.stats(ch); got.Dropped != 0 || got.Queued != 0 {
+		t.Errorf("stats = %+v, want nothing dropped or queued", got)
+	}
+}
+
+// TestMessageQueue_KeepLatest tests that PolicyKeepLatest messages keep
+// their order while the queue has room, and only the latest is delivered
+// once it is full.
+func TestMessageQueue_KeepLatest(t *testing.T) {
+	ch := make(chan Msg, 2)
+	q := newMessageQueue(defaultMessagePolicies())
+
+	q.post(ch, WindowSizeMsg{Width: 10, Height: 5})
+	q.post(ch, FocusMsg{})
+	q.post(ch, WindowSizeMsg{Width: 20, Height: 8})
+	q.post(ch, WindowSizeMsg{Width: 30, Height: 9})
+
+	msgs := q.drain(ch)
+	want := []Msg{WindowSizeMsg{Width: 10, Height: 5}, FocusMsg{}, WindowSizeMsg{Width: 30, Height: 9}}
+	if !reflect.DeepEqual(msgs, want) {
+		t.Errorf("drain = %v, want %v", msgs, want)
+	}
+
+	stats := q.stats(ch)
+	if stats.Coalesced != 1 || stats.Enqueued != 4 {
+		t.Errorf("stats = %+v, want 1 coalesced of 4 enqueued", stats)
+	}
+	if len(q.drain(ch)) != 0 {
+		t.Error("Expected an empty queue after drain")
+	}
+}
+
+// TestMessageQueue_KeepLatestOrder tests that a PolicyKeepLatest message
+// does not overtake a waiting message of its type or an overflowing
+// message, even when the channel has room again.
+func TestMessageQueue_KeepLatestOrder(t *testing.T) {
+	ch := make(chan Msg, 1)
+	q := newMessageQueue(defaultMessagePolicies())
+
+	q.post(ch, FocusMsg{})
+	q.post(ch, WindowSizeMsg{Width: 10, Height: 5})
+	<-ch
+	q.post(ch, WindowSizeMsg{Width: 20, Height: 8})
+	if len(ch) != 0 {
+		t.Error("Newer window size overtook the waiting one")
+	}
+
+	if msgs := q.drain(ch); !reflect.DeepEqual(msgs, []Msg{WindowSizeMsg{Width: 20, Height: 8}}) {
+		t.Errorf("drain = %v, want the latest window size", msgs)
+	}
+
+	q.post(ch, KeyMsg{Type: KeyEnter})
+	q.post(ch, KeyMsg{Type: KeyEsc})
+	<-ch
+	q.post(ch, WindowSizeMsg{Width: 30, Height: 9})
+	msgs := q.drain(ch)
+	want := []Msg{KeyMsg{Type: KeyEsc}, WindowSizeMsg{Width: 30, Height: 9}}
+	if !reflect.DeepEqual(msgs, want) {
+		t.Errorf("drain = %v, want %v", msgs, want)
+	}
+}
+
+// TestMessageQueue_SendWaits tests that PolicyWait messages sent to a full
+// queue wait for room until the context is cancelled.
+func TestMessageQueue_SendWaits(t *testing.T) {
+	ch := make(chan Msg, 1)
+	q := newMessageQueue(nil)
+	ch <- FocusMsg{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan bool)
+	go func() {
+		done <- q.send(ctx, ch, BlurMsg{})
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("Expected send to wait for room in the queue")
+	case <-time.After(20 * time.Millisecond):
+	}
+	cancel()
+	if <-done {
+		t.Error("Expected send to give up when the context is cancelled")
+	}
+}
+
+// TestProgram_MessagePolicyOptions tests the queue options of NewProgram.
+func TestProgram_MessagePolicyOptions(t *testing.T) {
+	p := NewProgram(nil,
+		WithQueueSize(2),
+		WithMessagePolicy(MouseMsg{}, PolicyKeepLatest),
+		WithMessagePolicy(KeyMsg{}, PolicyDrop),
+	)
+
+	if cap(p.msgChan) != 2 {
+		t.Errorf("queue size = %d, want 2", cap(p.msgChan))
+	}
+	if got := p.queue.policy(MouseMsg{}); got != PolicyKeepLatest {
+		t.Errorf("MouseMsg policy = %v, want %v", got, PolicyKeepLatest)
+	}
+	if got := p.queue.policy(KeyMsg{}); got != PolicyDrop {
+		t.Errorf("KeyMsg policy = %v, want %v", got, PolicyDrop)
+	}
+	if got := p.queue.policy(WindowSizeMsg{}); got != PolicyKeepLatest {
+		t.Errorf("WindowSizeMsg policy = %v, want default %v", got, PolicyKeepLatest)
+	}
+	if got := p.queue.policy(WindowClosedMsg{}); got != PolicyNeverDrop {
+		t.Errorf("WindowClosedMsg policy = %v, want default %v", got, PolicyNeverDrop)
+	}
+
+	p.Send(FocusMsg{})
+	if stats := p.QueueStats(); stats.Queued != 1 || stats.Enqueued != 1 {
+		t.Errorf("QueueStats = %+v, want 1 queued", stats)
+	}
+
+	for _, tc := range []struct {
+		name string
+		opt  ProgramOption
+	}{
+		{"queue size", WithQueueSize(0)},
+		{"max workers", WithMaxWorkers(-1)},
+	} {
+		if err := NewProgram(nil, tc.opt).validateOptions(); err == nil {
+			t.Errorf("Expected invalid %s to be rejected", tc.name)
+		}
+	}
+}
+
+// TestProgram_WindowEventsDoNotBlock tests that pointer events delivered on
+// the display thread are dropped instead of waiting for room in a full queue,
+// since only the display thread drains it.
+func TestProgram_WindowEventsDoNotBlock(t *testing.T) {
+	p := NewProgram(nil, WithQueueSize(1))
+	renderer, err := NewRenderer(RendererOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create renderer: %v", err)
+	}
+	p.renderer = renderer
+	p.Send(FocusMsg{})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		p.pointerEntered(0, 0)
+		p.Leave(nil, nil)
+		p.dragEnter([]string{"text/plain"}, 0, 0)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Pointer events blocked on a full queue")
+	}
+	if stats := p.QueueStats(); stats.Dropped != 3 || stats.Queued != 1 {
+		t.Errorf("QueueStats = %+v, want 3 dropped and 1 queued", stats)
+	}
+}
@@ -12,23 +12,47 @@ type Renderer struct {
 	defaultFg Color
 	defaultBg Color
 	lastGrid  *TerminalGrid
+
+	// lastWidth and lastHeight are the size of the surface last rendered
+	// to.
+	lastWidth  int32
+	lastHeight int32
+
+	// originX and originY are the pixel position of the top-left cell.
+	originX int32
+	originY int32
+
+	// bounds is the area of the surface the renderer draws into. A zero
+	// width or height means the whole surface.
+	boundsX      int32
+	boundsY      int32
+	boundsWidth  int32
+	boundsHeight int32
 }
 
 // RendererOptions configures the renderer.
 type RendererOptions struct {
 	DefaultFg Color
 	DefaultBg Color
+
+	// Font is the font of the cells. Nil loads the embedded font with its
+	// extended fonts.
+	Font *Font
 }
 
 // NewRenderer creates a new Renderer with the specified options.
 func NewRenderer(opts RendererOptions) (*Renderer, error) {
-	font, err := NewFont()
-	if err != nil {
-		return nil, fmt.Errorf("failed to load base font (ascii.png): %w (ensure font files are embedded)", err)
-	}
+	font := opts.Font
+	if font == nil {
+		var err error
+		font, err = NewFont()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load base font (ascii.png): %w (ensure font files are embedded)", err)
+		}
 
-	// Try to load extended fonts (optional, failures are ignored)
-	_ = font.LoadExtendedFonts()
+		// Try to load extended fonts (optional, failures are ignored)
+		_ = font.LoadExtendedFonts()
+	}
 
 	return &Renderer{
 		font:      font,
@@ -47,6 +71,23 @@ func (r *Renderer) CellHeight() int32 {
 	return int32(r.font.CellHeight())
 }
 
+// SetOrigin sets the pixel position of the top-left cell on the surface.
+// The area around the grid is filled with the default background color.
+func (r *Renderer) SetOrigin(x, y int32) {
+	r.originX = x
+	r.originY = y
+}
+
+// SetBounds limits drawing to a rectangle of the surface, for a grid that
+// shares the surface with other content. A zero width or height draws on
+// the whole surface.
+func (r *Renderer) SetBounds(x, y, width, height int32) {
+	r.boundsX = x
+	r.boundsY = y
+	r.boundsWidth = width
+	r.boundsHeight = height
+}
+
 // Render renders the entire terminal grid to the Cairo surface.
 func (r *Renderer) Render(grid *TerminalGrid, surface cairo.Surface) error {
 	if grid == nil {
@@ -67,6 +108,9 @@ func (r *Renderer) Render(grid *TerminalGrid, surface cairo.Surface) error {
 
 	Debug("Rendering grid: %dx%d cells to surface: %dx%d pixels", grid.Width, grid.Height, width, height)
 
+	// Fill the padding and leftover space around the grid
+	r.fillBorder(surface, grid)
+
 	// Render all cells - continue even if individual cells fail
 	for y := 0; y < grid.Height; y++ {
 		for x := 0; x < grid.Width; x++ {
@@ -77,6 +121,7 @@ func (r *Renderer) Render(grid *TerminalGrid, surface cairo.Surface) error {
 
 	// Store this grid for future diff operations
 	r.lastGrid = grid
+	r.lastWidth, r.lastHeight = int32(width), int32(height)
 
 	return nil
 }
@@ -106,22 +151,29 @@ func (r *Renderer) renderCell(surface cairo.Surface, gridX, gridY int, cell Cell
 	cellHeight := r.font.CellHeight()
 
 	// Calculate pixel position
-	pixelX := int32(gridX * cellWidth)
-	pixelY := int32(gridY * cellHeight)
+	pixelX := r.originX + int32(gridX*cellWidth)
+	pixelY := r.originY + int32(gridY*cellHeight)
 
-	// Get foreground and background colors
-	fg := cell.FgColor
-	if fg.IsDefault {
-		fg = r.defaultFg
-	}
-	bg := cell.BgColor
-	if bg.IsDefault {
-		bg = r.defaultBg
+	texture, bg, fg := r.cellTexture(gridX, gridY, cell)
+	if texture == nil {
+		return
 	}
 
+	// Render using the PutRGB method similar to the texteditor
+	r.putRGB(surface, pixelX, pixelY, texture, cellWidth, cellHeight, bg, fg)
+}
+
+// cellTexture returns the glyph texture of a cell and its resolved
+// background and foreground colors. The texture is nil if the cell cannot
+// be drawn.
+func (r *Renderer) cellTexture(gridX, gridY int, cell Cell) (texture [][3]byte, bg, fg [3]byte) {
+	// Get foreground and background colors
+	fgColor := resolve(cell.FgColor, r.defaultFg)
+	bgColor := resolve(cell.BgColor, r.defaultBg)
+
 	// Get the character texture
 	charStr := string(cell.Rune)
-	texture := r.font.GetRGBTexture(charStr)
+	texture = r.font.GetRGBTexture(charStr)
 
 	// Handle missing glyph - texture will be nil or a placeholder
 	if texture == nil {
@@ -131,13 +183,54 @@ func (r *Renderer) renderCell(surface cairo.Surface, gridX, gridY int, cell Cell
 		if texture == nil {
 			// If even space is missing, skip rendering this cell
 			Warn("Font missing space character, skipping cell at (%d, %d)", gridX, gridY)
-			return
 		}
 	}
 
-	// Render using the PutRGB method similar to the texteditor
-	r.putRGB(surface, pixelX, pixelY, texture, cellWidth, cellHeight, 
-		[3]byte{bg.R, bg.G, bg.B}, [3]byte{fg.R, fg.G, fg.B})
+	return texture, [3]byte{bgColor.R, bgColor.G, bgColor.B}, [3]byte{fgColor.R, fgColor.G, fgColor.B}
+}
+
+// fillBorder fills the bounds outside the grid with the default background
+// color.
+func (r *Renderer) fillBorder(surface cairo.Surface, grid *TerminalGrid) {
+	x0, y0 := r.boundsX, r.boundsY
+	x1, y1 := x0+r.boundsWidth, y0+r.boundsHeight
+	if r.boundsWidth <= 0 || r.boundsHeight <= 0 {
+		x0, y0 = 0, 0
+		x1 = int32(surface.ImageSurfaceGetWidth())
+		y1 = int32(surface.ImageSurfaceGetHeight())
+	}
+	left := r.originX
+	top := r.originY
+	right := left + int32(grid.Width*r.font.CellWidth())
+	bottom := top + int32(grid.Height*r.font.CellHeight())
+
+	r.fillRect(surface, x0, y0, x1-x0, top-y0)
+	r.fillRect(surface, x0, bottom, x1-x0, y1-bottom)
+	r.fillRect(surface, x0, top, left-x0, bottom-top)
+	r.fillRect(surface, right, top, x1-right, bottom-top)
+}
+
+// fillRect fills a rectangle of the surface with the default background
+// color. The rectangle is clipped to the surface.
+func (r *Renderer) fillRect(surface cairo.Surface, x, y, w, h int32) {
+	dst8 := surface.ImageSurfaceGetData()
+	width := int32(surface.ImageSurfaceGetWidth())
+	height := int32(surface.ImageSurfaceGetHeight())
+	stride := int32(surface.ImageSurfaceGetStride())
+
+	x0, y0 := max(x, 0), max(y, 0)
+	x1, y1 := min(x+w, width), min(y+h, height)
+	bg := r.defaultBg
+	for py := y0; py < y1; py++ {
+		for px := x0; px < x1; px++ {
+			pos := py*stride + px*4
+			// Cairo uses BGRA format
+			dst8[pos] = bg.B
+			dst8[pos+1] = bg.G
+			dst8[pos+2] = bg.R
+			dst8[pos+3] = 255
+		}
+	}
 }
 
 // putRGB renders an RGB texture to the Cairo surface at the specified position.
@@ -165,32 +258,20 @@ func (r *Renderer) putRGB(surface cairo.Surface, posX, posY int32,
 			}
 
 			// Cairo uses BGRA format
-			dst8[dstPos] = textureRGB[srcPos][2]     // B
-			dst8[dstPos+1] = textureRGB[srcPos][1]   // G
-			dst8[dstPos+2] = textureRGB[srcPos][0]   // R
-			dst8[dstPos+3] = 255                      // A
-
-			// Apply background color (minimum values)
-			if dst8[dstPos] < bg[2] {
-				dst8[dstPos] = bg[2]
-			}
-			if dst8[dstPos+1] < bg[1] {
-				dst8[dstPos+1] = bg[1]
-			}
-			if dst8[dstPos+2] < bg[0] {
-				dst8[dstPos+2] = bg[0]
-			}
-
-			// Apply foreground color (maximum values)
-			if dst8[dstPos] > fg[2] {
-				dst8[dstPos] = fg[2]
-			}
-			if dst8[dstPos+1] > fg[1] {
-				dst8[dstPos+1] = fg[1]
-			}
-			if dst8[dstPos+2] > fg[0] {
-				dst8[dstPos+2] = fg[0]
-			}
+			rgb := shade(textureRGB[srcPos], bg, fg)
+			dst8[dstPos] = rgb[2]   // B
+			dst8[dstPos+1] = rgb[1] // G
+			dst8[dstPos+2] = rgb[0] // R
+			dst8[dstPos+3] = 255    // A
 		}
 	}
 }
+
+// shade colors a texture pixel: the background color is the minimum and the
+// foreground color the maximum of each channel.
+func shade(texel, bg, fg [3]byte) [3]byte {
+	for c := range texel {
+		texel[c] = min(max(texel[c], bg[c]), fg[c])
+	}
+	return texel
+}
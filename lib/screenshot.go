@@ -0,0 +1,84 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// screenshotMsg is an internal message that saves the window to a PNG file.
+type screenshotMsg struct {
+	path string
+}
+
+// ScreenshotMsg is sent to the model after the Screenshot command or the
+// screenshot key saved the window to a PNG file. Err is set if it could
+// not be saved.
+type ScreenshotMsg struct {
+	Path string
+	Err  error
+}
+
+// String returns a string representation of the screenshot message for debugging.
+func (s ScreenshotMsg) String() string {
+	if s.Err != nil {
+		return fmt.Sprintf("ScreenshotMsg{Path: %q, Err: %v}", s.Path, s.Err)
+	}
+	return fmt.Sprintf("ScreenshotMsg{Path: %q}", s.Path)
+}
+
+// Screenshot returns a command that saves the current frame of the window
+// to a PNG file at path. The model receives a ScreenshotMsg once the file
+// is written.
+func Screenshot(path string) Cmd {
+	return func() Msg {
+		return screenshotMsg{path: path}
+	}
+}
+
+// Screenshot returns the last frame rendered to the window, including the
+// padding around the grid. It is safe to call from Update and from other
+// goroutines.
+func (p *Program) Screenshot() (image.Image, error) {
+	p.renderMu.Lock()
+	defer p.renderMu.Unlock()
+
+	if p.renderer == nil {
+		return nil, errors.New("program is not running")
+	}
+	img := p.renderer.Snapshot()
+	if img == nil {
+		return nil, errors.New("no frame has been rendered yet")
+	}
+	return img, nil
+}
+
+// saveScreenshot takes a screenshot and writes it to path in a command, so
+// the display thread does not wait for the file. Missing directories are
+// created.
+func (p *Program) saveScreenshot(path string) {
+	img, err := p.Screenshot()
+	p.cmdExec.Execute(func() Msg {
+		if err == nil {
+			err = os.MkdirAll(filepath.Dir(path), 0o755)
+		}
+		if err == nil {
+			err = WritePNG(path, img)
+		}
+		if err != nil {
+			Warn("Failed to save screenshot %s: %v", path, err)
+		} else {
+			Info("Saved screenshot %s", path)
+		}
+		return ScreenshotMsg{Path: path, Err: err}
+	})
+}
+
+// screenshotName returns the file name of a screenshot taken with the
+// screenshot key at t.
+func screenshotName(t time.Time) string {
+	return t.Format("screenshot-20060102-150405.000.png")
+}
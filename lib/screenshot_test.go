@@ -0,0 +1,158 @@
+package lib
+
+import (
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	cairo "github.com/neurlang/wayland/cairoshim"
+)
+
+// renderToSurface renders a view with the program's renderer to a
+// simulated surface of the given size, like a frame of the window.
+func renderToSurface(t *testing.T, r *Renderer, view string, width, height int) cairo.Surface {
+	t.Helper()
+	stride := cairo.FormatStrideForWidth(cairo.FormatArgb32, width)
+	surface := cairo.ImageSurfaceCreateForData(make([]byte, stride*height), cairo.FormatArgb32, width, height, stride)
+
+	cols := (width - 2*int(r.originX)) / int(r.CellWidth())
+	rows := (height - 2*int(r.originY)) / int(r.CellHeight())
+	if err := r.Render(ParseANSI(view, cols, rows), surface); err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	return surface
+}
+
+func TestRenderer_Snapshot(t *testing.T) {
+	r, err := NewRenderer(RendererOptions{DefaultFg: NewColor(255, 255, 255), DefaultBg: NewColor(0, 0, 64)})
+	if err != nil {
+		t.Fatalf("NewRenderer failed: %v", err)
+	}
+	if r.Snapshot() != nil {
+		t.Error("Expected no snapshot before the first render")
+	}
+
+	r.SetOrigin(5, 3)
+	surface := renderToSurface(t, r, "\x1b[31mSnap\x1b[0m shot", 130, 70)
+	img := r.Snapshot()
+
+	if img.Bounds().Dx() != 130 || img.Bounds().Dy() != 70 {
+		t.Fatalf("Snapshot size = %v, want 130x70", img.Bounds())
+	}
+	data := surface.ImageSurfaceGetData()
+	stride := surface.ImageSurfaceGetStride()
+	for y := 0; y < 70; y++ {
+		for x := 0; x < 130; x++ {
+			pos := y*stride + x*4
+			c := img.RGBAAt(x, y)
+			// The surface is BGRA
+			if c.R != data[pos+2] || c.G != data[pos+1] || c.B != data[pos] {
+				t.Fatalf("Pixel %d,%d = %v, surface has %v", x, y, c, data[pos:pos+4])
+			}
+		}
+	}
+}
+
+func TestProgram_Screenshot(t *testing.T) {
+	p := NewProgram(logModel{})
+	if _, err := p.Screenshot(); err == nil {
+		t.Error("Expected an error before the program runs")
+	}
+
+	if err := p.createRenderer(); err != nil {
+		t.Fatalf("createRenderer failed: %v", err)
+	}
+	defer p.cmdExec.Shutdown()
+	if _, err := p.Screenshot(); err == nil {
+		t.Error("Expected an error before the first frame")
+	}
+
+	renderToSurface(t, p.renderer, "hello", 80, 40)
+	img, err := p.Screenshot()
+	if err != nil {
+		t.Fatalf("Screenshot failed: %v", err)
+	}
+	if img.Bounds().Dx() != 80 || img.Bounds().Dy() != 40 {
+		t.Errorf("Screenshot size = %v, want 80x40", img.Bounds())
+	}
+}
+
+// screenshotResult waits for the ScreenshotMsg of a saved screenshot.
+func screenshotResult(t *testing.T, p *Program) ScreenshotMsg {
+	t.Helper()
+	select {
+	case msg := <-p.msgChan:
+		result, ok := msg.(ScreenshotMsg)
+		if !ok {
+			t.Fatalf("Expected ScreenshotMsg, got %T", msg)
+		}
+		return result
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for ScreenshotMsg")
+		return ScreenshotMsg{}
+	}
+}
+
+func TestProgram_ScreenshotCmd(t *testing.T) {
+	p := NewProgram(logModel{})
+	if err := p.createRenderer(); err != nil {
+		t.Fatalf("createRenderer failed: %v", err)
+	}
+	defer p.cmdExec.Shutdown()
+	renderToSurface(t, p.renderer, "hello", 80, 40)
+
+	path := filepath.Join(t.TempDir(), "shots", "frame.png")
+	if !p.handleProgramMsg(Screenshot(path)()) {
+		t.Fatal("Screenshot message was not consumed by the Program")
+	}
+	result := screenshotResult(t, p)
+	if result.Path != path || result.Err != nil {
+		t.Fatalf("ScreenshotMsg = %v, want %q without error", result, path)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Screenshot file: %v", err)
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		t.Fatalf("Screenshot is not a PNG: %v", err)
+	}
+	want, _ := p.Screenshot()
+	if diff := DiffImages(want, img, 0); !diff.Equal() {
+		t.Errorf("Saved screenshot differs from the frame in %d pixels", diff.Pixels)
+	}
+}
+
+func TestProgram_ScreenshotKey(t *testing.T) {
+	key := KeyMsg{Type: KeyPrintScreen}
+	if NewProgram(logModel{}).handleProgramMsg(key) {
+		t.Error("Print Screen was consumed without a screenshot directory")
+	}
+
+	dir := t.TempDir()
+	p := NewProgram(logModel{}, WithScreenshotDir(dir), WithClock(NewFakeClock(fakeStart)))
+	if err := p.createRenderer(); err != nil {
+		t.Fatalf("createRenderer failed: %v", err)
+	}
+	defer p.cmdExec.Shutdown()
+	renderToSurface(t, p.renderer, "hello", 80, 40)
+
+	if !p.handleProgramMsg(key) {
+		t.Fatal("Print Screen was not consumed")
+	}
+	result := screenshotResult(t, p)
+	if want := filepath.Join(dir, "screenshot-20240101-120000.000.png"); result.Path != want || result.Err != nil {
+		t.Fatalf("ScreenshotMsg = %v, want %q without error", result, want)
+	}
+	if _, err := os.Stat(result.Path); err != nil {
+		t.Errorf("Screenshot file: %v", err)
+	}
+
+	if p.handleProgramMsg(KeyMsg{Type: KeyF12}) {
+		t.Error("Other keys must reach Update")
+	}
+}
@@ -0,0 +1,187 @@
+package lib
+
+import (
+	"strings"
+	"unicode"
+)
+
+// selectionUnit is the granularity a selection grows by while dragging.
+type selectionUnit int
+
+const (
+	selectCells selectionUnit = iota
+	selectWords
+	selectLines
+)
+
+// selection tracks the built-in mouse text selection over the rendered grid.
+// Positions are in cells. The selection runs in reading order from the
+// anchor to the cursor, like a terminal selection, and is widened to whole
+// words or lines depending on how it was started.
+type selection struct {
+	unit     selectionUnit
+	anchorX  int
+	anchorY  int
+	cursorX  int
+	cursorY  int
+	dragging bool
+	active   bool
+}
+
+// start begins a new selection at the given cell. Word and line selections
+// are active immediately; cell selections become active once the pointer
+// is dragged to another cell.
+func (s *selection) start(x, y int, unit selectionUnit) {
+	s.unit = unit
+	s.anchorX, s.anchorY = x, y
+	s.cursorX, s.cursorY = x, y
+	s.dragging = true
+	s.active = unit != selectCells
+}
+
+// extend moves the end of the selection being dragged to the given cell.
+// It reports whether the selection changed.
+func (s *selection) extend(x, y int) bool {
+	if !s.dragging || (x == s.cursorX && y == s.cursorY) {
+		return false
+	}
+	s.cursorX, s.cursorY = x, y
+	s.active = true
+	return true
+}
+
+// finish ends dragging. It reports whether there is a selection to copy.
+func (s *selection) finish() bool {
+	s.dragging = false
+	return s.active
+}
+
+// clear removes the selection. It reports whether there was one.
+func (s *selection) clear() bool {
+	had := s.active
+	s.active = false
+	s.dragging = false
+	return had
+}
+
+// bounds returns the first and last selected cells in reading order, widened
+// to the selection unit. The end cell is inclusive.
+func (s *selection) bounds(grid *TerminalGrid) (startX, startY, endX, endY int) {
+	startX, startY, endX, endY = s.anchorX, s.anchorY, s.cursorX, s.cursorY
+	if endY < startY || (endY == startY && endX < startX) {
+		startX, startY, endX, endY = endX, endY, startX, startY
+	}
+
+	switch s.unit {
+	case selectWords:
+		startX, _ = wordBounds(grid, startX, startY)
+		_, endX = wordBounds(grid, endX, endY)
+	case selectLines:
+		startX = 0
+		endX = grid.Width - 1
+	}
+	return startX, startY, endX, endY
+}
+
+// highlight recolors the selected cells of grid with the theme's selection
+// colors. Default selection colors swap each cell's foreground and
+// background.
+func (s *selection) highlight(grid *TerminalGrid, theme Theme) {
+	if !s.active || grid == nil {
+		return
+	}
+
+	startX, startY, endX, endY := s.bounds(grid)
+	for y := startY; y <= endY && y < grid.Height; y++ {
+		if y < 0 {
+			continue
+		}
+		from, to := lineSpan(grid, y, startX, startY, endX, endY)
+		for x := from; x <= to; x++ {
+			cell := &grid.Cells[y][x]
+			fg := resolve(cell.FgColor, theme.Foreground)
+			bg := resolve(cell.BgColor, theme.Background)
+			cell.FgColor = resolve(theme.SelectionForeground, bg)
+			cell.BgColor = resolve(theme.SelectionBackground, fg)
+		}
+	}
+}
+
+// text returns the selected text of grid. Trailing spaces are trimmed from
+// each line and lines are joined with newlines.
+func (s *selection) text(grid *TerminalGrid) string {
+	if !s.active || grid == nil {
+		return ""
+	}
+
+	startX, startY, endX, endY := s.bounds(grid)
+	var lines []string
+	for y := startY; y <= endY && y < grid.Height; y++ {
+		if y < 0 {
+			continue
+		}
+		from, to := lineSpan(grid, y, startX, startY, endX, endY)
+		var line strings.Builder
+		for x := from; x <= to; x++ {
+			r := grid.Cells[y][x].Rune
+			if r == 0 {
+				r = ' '
+			}
+			line.WriteRune(r)
+		}
+		lines = append(lines, strings.TrimRight(line.String(), " "))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// lineSpan returns the first and last selected columns of row y, clamped to
+// the grid width.
+func lineSpan(grid *TerminalGrid, y, startX, startY, endX, endY int) (int, int) {
+	from, to := 0, grid.Width-1
+	if y == startY {
+		from = startX
+	}
+	if y == endY {
+		to = endX
+	}
+	if from < 0 {
+		from = 0
+	}
+	if to >= grid.Width {
+		to = grid.Width - 1
+	}
+	return from, to
+}
+
+// wordBounds returns the first and last columns of the word at the given
+// cell. A cell that is not part of a word is returned on its own, or with
+// the run of identical characters it belongs to (such as spaces).
+func wordBounds(grid *TerminalGrid, x, y int) (int, int) {
+	cell := grid.GetCell(x, y)
+	if cell == nil {
+		return x, x
+	}
+
+	same := func(r rune) bool { return r == cell.Rune }
+	if isWordRune(cell.Rune) {
+		same = isWordRune
+	}
+
+	start, end := x, x
+	for start > 0 && same(grid.Cells[y][start-1].Rune) {
+		start--
+	}
+	for end < grid.Width-1 && same(grid.Cells[y][end+1].Rune) {
+		end++
+	}
+	return start, end
+}
+
+// isWordRune reports whether r is part of a word for double-click selection.
+// Characters common in paths and URLs are included so they select as one.
+func isWordRune(r rune) bool {
+	if unicode.IsLetter(r) || unicode.IsDigit(r) {
+		return true
+	}
+	return strings.ContainsRune("_-./~:@%+=?&#", r)
+}
@@ -0,0 +1,154 @@
+package lib
+
+import "testing"
+
+func selectionGrid() *TerminalGrid {
+	return ParseANSI("hello world\nsecond line here\nthird", 20, 3)
+}
+
+func TestSelection_CellText(t *testing.T) {
+	grid := selectionGrid()
+	var s selection
+
+	s.start(6, 0, selectCells)
+	if s.active {
+		t.Fatal("cell selection active before dragging")
+	}
+	s.extend(5, 1)
+	if !s.finish() {
+		t.Fatal("expected an active selection after dragging")
+	}
+
+	want := "world\nsecond"
+	if got := s.text(grid); got != want {
+		t.Errorf("text = %q, want %q", got, want)
+	}
+}
+
+func TestSelection_BackwardsDrag(t *testing.T) {
+	grid := selectionGrid()
+	var s selection
+
+	s.start(4, 0, selectCells)
+	s.extend(0, 0)
+	if got := s.text(grid); got != "hello" {
+		t.Errorf("text = %q, want %q", got, "hello")
+	}
+}
+
+func TestSelection_Words(t *testing.T) {
+	grid := selectionGrid()
+	var s selection
+
+	s.start(8, 0, selectWords)
+	if got := s.text(grid); got != "world" {
+		t.Errorf("word text = %q, want %q", got, "world")
+	}
+
+	s.extend(2, 1)
+	if got := s.text(grid); got != "world\nsecond" {
+		t.Errorf("extended word text = %q, want %q", got, "world\nsecond")
+	}
+}
+
+func TestSelection_Lines(t *testing.T) {
+	grid := selectionGrid()
+	var s selection
+
+	s.start(3, 1, selectLines)
+	s.extend(0, 2)
+	if got := s.text(grid); got != "second line here\nthird" {
+		t.Errorf("line text = %q, want %q", got, "second line here\nthird")
+	}
+}
+
+func TestWordBounds(t *testing.T) {
+	grid := ParseANSI("see /usr/lib ok", 20, 1)
+
+	tests := []struct {
+		x          int
+		start, end int
+	}{
+		{1, 0, 2},
+		{6, 4, 11},
+		{3, 3, 3},
+		{17, 15, 19},
+	}
+
+	for _, tt := range tests {
+		start, end := wordBounds(grid, tt.x, 0)
+		if start != tt.start || end != tt.end {
+			t.Errorf("wordBounds(%d) = (%d, %d), want (%d, %d)", tt.x, start, end, tt.start, tt.end)
+		}
+	}
+}
+
+func TestSelection_Highlight(t *testing.T) {
+	grid := selectionGrid()
+	var s selection
+	s.start(0, 0, selectCells)
+	s.extend(1, 0)
+
+	// Default selection colors swap foreground and background
+	s.highlight(grid, DefaultTheme())
+	cell := grid.GetCell(0, 0)
+	if cell.FgColor != NewColor(0, 0, 0) || cell.BgColor != NewColor(255, 255, 255) {
+		t.Errorf("reverse video cell colors = %v/%v", cell.FgColor, cell.BgColor)
+	}
+	if cell := grid.GetCell(2, 0); !cell.FgColor.IsDefault || !cell.BgColor.IsDefault {
+		t.Errorf("unselected cell was recolored: %v/%v", cell.FgColor, cell.BgColor)
+	}
+
+	// Theme selection colors are used as-is
+	grid = selectionGrid()
+	theme := DefaultTheme()
+	theme.SelectionForeground = NewColor(1, 2, 3)
+	theme.SelectionBackground = NewColor(4, 5, 6)
+	s.highlight(grid, theme)
+	cell = grid.GetCell(1, 0)
+	if cell.FgColor != theme.SelectionForeground || cell.BgColor != theme.SelectionBackground {
+		t.Errorf("themed cell colors = %v/%v", cell.FgColor, cell.BgColor)
+	}
+}
+
+func TestProgram_SelectionButtons(t *testing.T) {
+	p := NewProgram(nil)
+	p.grid = selectionGrid()
+
+	press := MouseMsg{X: 8, Y: 0, Type: MousePress, Button: MouseButtonLeft}
+	release := MouseMsg{X: 8, Y: 0, Type: MouseRelease, Button: MouseButtonLeft}
+
+	// Without Shift the click goes to the model
+	if p.handleSelectionButton(press, false, 1000) {
+		t.Fatal("plain click was used by the selection")
+	}
+
+	// Shift+double-click selects a word
+	p.handleSelectionButton(press, true, 2000)
+	p.handleSelectionButton(release, true, 2010)
+	if !p.handleSelectionButton(press, true, 2100) {
+		t.Fatal("Shift+click was not used by the selection")
+	}
+	p.handleSelectionButton(release, true, 2110)
+	if got := p.selection.text(p.grid); got != "world" {
+		t.Errorf("selected text = %q, want %q", got, "world")
+	}
+
+	// A plain click clears the selection
+	p.handleSelectionButton(press, false, 5000)
+	if p.selection.active {
+		t.Error("selection still active after a plain click")
+	}
+
+	// With mouse reporting off, a plain drag selects
+	p = NewProgram(nil, WithoutMouse())
+	if !p.handleSelectionButton(press, false, 1000) {
+		t.Error("click with mouse reporting off was not used by the selection")
+	}
+
+	// The selection can be disabled
+	p = NewProgram(nil, WithoutMouse(), WithoutMouseSelection())
+	if p.handleSelectionButton(press, true, 1000) {
+		t.Error("click was used by a disabled selection")
+	}
+}
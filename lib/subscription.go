@@ -0,0 +1,168 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// SubscriptionFunc produces messages for a subscription until ctx is
+// cancelled. It calls send for every message and returns when the source
+// ends. A non-nil error restarts the subscription.
+type SubscriptionFunc func(ctx context.Context, send func(Msg)) error
+
+// Subscription restart delays. The delay doubles after every failure that
+// sent no messages, up to the maximum.
+const (
+	subscriptionMinRestartDelay = 100 * time.Millisecond
+	subscriptionMaxRestartDelay = 10 * time.Second
+)
+
+// Subscribe creates a command that starts a long-running source of
+// messages, such as a file watcher, a log tail or a websocket stream. The
+// function runs until it returns, the subscription is stopped with
+// Unsubscribe(key), or the program quits.
+//
+// If the function returns an error or panics, the model receives a
+// SubscriptionErrorMsg and the function is started again after a delay.
+// Subscribing with the key of a running subscription does nothing, so the
+// command can be returned again from Update.
+func Subscribe(key string, fn SubscriptionFunc) Cmd {
+	return func() Msg {
+		return subscribeMsg{key: key, fn: fn}
+	}
+}
+
+// subscribeMsg is the internal message type for starting subscriptions.
+type subscribeMsg struct {
+	key string
+	fn  SubscriptionFunc
+}
+
+// Unsubscribe creates a command that stops the subscription with the given
+// key. Messages it sends afterwards are dropped. Unsubscribing a key that is
+// not running does nothing.
+func Unsubscribe(key string) Cmd {
+	return func() Msg {
+		return unsubscribeMsg{key: key}
+	}
+}
+
+// unsubscribeMsg is the internal message type for stopping subscriptions.
+type unsubscribeMsg struct {
+	key string
+}
+
+// SubscriptionErrorMsg is sent when a subscription function returns an
+// error or panics. The subscription is restarted after RetryIn.
+type SubscriptionErrorMsg struct {
+	Key     string
+	Err     error
+	RetryIn time.Duration
+}
+
+// String returns a string representation of the subscription error for debugging.
+func (s SubscriptionErrorMsg) String() string {
+	return fmt.Sprintf("SubscriptionErrorMsg{Key: %q, Err: %v, RetryIn: %v}", s.Key, s.Err, s.RetryIn)
+}
+
+// subscribe starts the subscription unless one with the same key is running.
+func (ce *CommandExecutor) subscribe(m subscribeMsg) {
+	ce.mu.Lock()
+	if _, ok := ce.subscriptions[m.key]; ok {
+		ce.mu.Unlock()
+		Debug("Subscription %q already running", m.key)
+		return
+	}
+	ctx, cancel := context.WithCancel(ce.cmdCtx)
+	ce.subscriptions[m.key] = cancel
+	ce.mu.Unlock()
+
+	Debug("Starting subscription %q", m.key)
+	ce.wg.Add(1)
+	run := ce.track(m.key)
+	go func() {
+		defer ce.wg.Done()
+		defer ce.untrack(run)
+		defer cancel()
+		defer func() {
+			ce.mu.Lock()
+			// A new subscription may have taken the key after Unsubscribe
+			if ctx.Err() == nil {
+				delete(ce.subscriptions, m.key)
+			}
+			ce.mu.Unlock()
+			Debug("Subscription %q stopped", m.key)
+		}()
+
+		ce.runSubscription(ctx, m)
+	}()
+}
+
+// runSubscription calls the subscription function until it returns without
+// an error or ctx is cancelled, waiting between restarts.
+func (ce *CommandExecutor) runSubscription(ctx context.Context, m subscribeMsg) {
+	delay := subscriptionMinRestartDelay
+	for {
+		// send may be called from goroutines started by the function
+		var sent atomic.Bool
+		send := func(msg Msg) {
+			if msg == nil || ctx.Err() != nil {
+				return
+			}
+			sent.Store(true)
+			if !ce.queue.send(ctx, ce.msgChan, msg) {
+				Debug("Subscription %q message not delivered", m.key)
+			}
+		}
+
+		err := callSubscription(ctx, m.fn, send)
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil {
+			Debug("Subscription %q ended", m.key)
+			return
+		}
+
+		if sent.Load() {
+			delay = subscriptionMinRestartDelay
+		}
+		Warn("Subscription %q failed, restarting in %v: %v", m.key, delay, err)
+		ce.deliverMessage(SubscriptionErrorMsg{Key: m.key, Err: err, RetryIn: delay})
+
+		timer := ce.clock.NewTimer(delay)
+		select {
+		case <-timer.C():
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+		delay = min(delay*2, subscriptionMaxRestartDelay)
+	}
+}
+
+// callSubscription calls a subscription function and turns a panic into an
+// error.
+func callSubscription(ctx context.Context, fn SubscriptionFunc, send func(Msg)) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			Error("Subscription panicked: %v", r)
+			err = fmt.Errorf("subscription panic: %v", r)
+		}
+	}()
+	return fn(ctx, send)
+}
+
+// unsubscribe stops the subscription with the given key.
+func (ce *CommandExecutor) unsubscribe(key string) {
+	ce.mu.Lock()
+	defer ce.mu.Unlock()
+
+	if cancel, ok := ce.subscriptions[key]; ok {
+		Debug("Stopping subscription %q", key)
+		cancel()
+		delete(ce.subscriptions, key)
+	}
+}
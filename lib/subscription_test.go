@@ -0,0 +1,180 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestSubscribe tests that a subscription delivers every message it sends.
+func TestSubscribe(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	msgChan := make(chan Msg, 10)
+	executor := NewCommandExecutor(ctx, msgChan)
+
+	executor.Execute(Subscribe("lines", func(ctx context.Context, send func(Msg)) error {
+		for _, line := range []string{"a", "b", "c"} {
+			send(line)
+		}
+		return nil
+	}))
+
+	for _, exp := range []string{"a", "b", "c"} {
+		select {
+		case msg := <-msgChan:
+			if msg != exp {
+				t.Errorf("Expected message %q, got %v", exp, msg)
+			}
+		case <-time.After(1 * time.Second):
+			t.Fatalf("Timeout waiting for message %q", exp)
+		}
+	}
+	executor.Shutdown()
+}
+
+// TestSubscribe_Duplicate tests that subscribing with a running key does not
+// start a second subscription.
+func TestSubscribe_Duplicate(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	msgChan := make(chan Msg, 10)
+	executor := NewCommandExecutor(ctx, msgChan)
+
+	var starts atomic.Int32
+	started := make(chan struct{}, 2)
+	sub := Subscribe("watch", func(ctx context.Context, send func(Msg)) error {
+		starts.Add(1)
+		started <- struct{}{}
+		<-ctx.Done()
+		return nil
+	})
+
+	executor.Execute(sub)
+	<-started
+	executor.Execute(sub)
+	time.Sleep(20 * time.Millisecond)
+
+	if n := starts.Load(); n != 1 {
+		t.Errorf("Expected 1 running subscription, got %d", n)
+	}
+	if err := executor.ShutdownTimeout(time.Second); err != nil {
+		t.Errorf("Expected subscription to stop on shutdown, got %v", err)
+	}
+}
+
+// TestUnsubscribe tests that Unsubscribe stops a subscription and drops its
+// later messages.
+func TestUnsubscribe(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	msgChan := make(chan Msg, 10)
+	executor := NewCommandExecutor(ctx, msgChan)
+	defer executor.Shutdown()
+
+	stopped := make(chan struct{})
+	executor.Execute(Subscribe("ticks", func(ctx context.Context, send func(Msg)) error {
+		defer close(stopped)
+		for ctx.Err() == nil {
+			send("tick")
+			time.Sleep(5 * time.Millisecond)
+		}
+		send("late")
+		return nil
+	}))
+
+	select {
+	case <-msgChan:
+	case <-time.After(1 * time.Second):
+		t.Fatal("Timeout waiting for the first message")
+	}
+
+	executor.Execute(Unsubscribe("ticks"))
+
+	select {
+	case <-stopped:
+	case <-time.After(1 * time.Second):
+		t.Fatal("Timeout waiting for the subscription to stop")
+	}
+
+	for len(msgChan) > 0 {
+		if msg := <-msgChan; msg == "late" {
+			t.Error("Expected messages after Unsubscribe to be dropped")
+		}
+	}
+}
+
+// TestSubscribe_Restart tests that a failing subscription is reported and
+// started again.
+func TestSubscribe_Restart(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	msgChan := make(chan Msg, 10)
+	executor := NewCommandExecutor(ctx, msgChan)
+	defer executor.Shutdown()
+
+	errLost := errors.New("connection lost")
+	var runs atomic.Int32
+	executor.Execute(Subscribe("stream", func(ctx context.Context, send func(Msg)) error {
+		if runs.Add(1) == 1 {
+			return errLost
+		}
+		send("reconnected")
+		return nil
+	}))
+
+	select {
+	case msg := <-msgChan:
+		subErr, ok := msg.(SubscriptionErrorMsg)
+		if !ok {
+			t.Fatalf("Expected SubscriptionErrorMsg, got %T", msg)
+		}
+		if subErr.Key != "stream" || !errors.Is(subErr.Err, errLost) {
+			t.Errorf("Unexpected subscription error %v", subErr)
+		}
+		if subErr.RetryIn != subscriptionMinRestartDelay {
+			t.Errorf("Expected retry in %v, got %v", subscriptionMinRestartDelay, subErr.RetryIn)
+		}
+	case <-time.After(1 * time.Second):
+		t.Fatal("Timeout waiting for the subscription error")
+	}
+
+	select {
+	case msg := <-msgChan:
+		if msg != "reconnected" {
+			t.Errorf("Expected message %q, got %v", "reconnected", msg)
+		}
+	case <-time.After(1 * time.Second):
+		t.Fatal("Timeout waiting for the restarted subscription")
+	}
+}
+
+// TestSubscribe_Panic tests that a panicking subscription is reported.
+func TestSubscribe_Panic(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	msgChan := make(chan Msg, 10)
+	executor := NewCommandExecutor(ctx, msgChan)
+	defer executor.Shutdown()
+
+	executor.Execute(Subscribe("bad", func(ctx context.Context, send func(Msg)) error {
+		panic("boom")
+	}))
+
+	select {
+	case msg := <-msgChan:
+		if _, ok := msg.(SubscriptionErrorMsg); !ok {
+			t.Errorf("Expected SubscriptionErrorMsg, got %T", msg)
+		}
+	case <-time.After(1 * time.Second):
+		t.Fatal("Timeout waiting for the subscription error")
+	}
+	executor.Execute(Unsubscribe("bad"))
+}
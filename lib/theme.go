@@ -0,0 +1,40 @@
+package lib
+
+// Theme specifies the colors the Program uses for content that does not set
+// its own colors, and for the built-in text selection.
+type Theme struct {
+	// Foreground is the text color used for cells with the default
+	// foreground color.
+	Foreground Color
+
+	// Background is the color used for cells with the default background
+	// color.
+	Background Color
+
+	// SelectionForeground is the text color of selected cells. A default
+	// color uses the cell's background color (reverse video).
+	SelectionForeground Color
+
+	// SelectionBackground is the background color of selected cells. A
+	// default color uses the cell's foreground color (reverse video).
+	SelectionBackground Color
+}
+
+// DefaultTheme returns the theme used when none is configured: white text on
+// a black background, with selections shown in reverse video.
+func DefaultTheme() Theme {
+	return Theme{
+		Foreground:          NewColor(255, 255, 255),
+		Background:          NewColor(0, 0, 0),
+		SelectionForeground: DefaultColor(),
+		SelectionBackground: DefaultColor(),
+	}
+}
+
+// resolve returns c, or fallback if c is the default color.
+func resolve(c, fallback Color) Color {
+	if c.IsDefault {
+		return fallback
+	}
+	return c
+}
@@ -0,0 +1,197 @@
+package lib
+
+import (
+	"math"
+	"time"
+)
+
+// Gesture recognition tuning.
+const (
+	// tapSlop is the distance in pixels a finger may move and still count
+	// as a tap or long press.
+	tapSlop = 10.0
+
+	// longPressDelay is how long a finger must stay down without moving to
+	// count as a long press.
+	longPressDelay = 500 * time.Millisecond
+
+	// swipeMinDistance is the distance in pixels two fingers must travel
+	// together to count as a swipe.
+	swipeMinDistance = 30.0
+
+	// pinchMinChange is the relative change in finger distance that counts
+	// as a pinch rather than a swipe.
+	pinchMinChange = 0.15
+)
+
+// touchPoint is a touch position in pixels.
+type touchPoint struct {
+	x float32
+	y float32
+}
+
+// distance returns the distance in pixels between two touch points.
+func (t touchPoint) distance(o touchPoint) float64 {
+	return math.Hypot(float64(o.x-t.x), float64(o.y-t.y))
+}
+
+// centroid returns the point between the first two touch points in ids order.
+func centroid(points map[int32]touchPoint, ids []int32) touchPoint {
+	a, b := points[ids[0]], points[ids[1]]
+	return touchPoint{x: (a.x + b.x) / 2, y: (a.y + b.y) / 2}
+}
+
+// gestureRecognizer recognizes taps, long presses, and two-finger swipes and
+// pinches from a stream of touch events. A gesture starts when the first
+// finger goes down and is decided when the first finger is lifted.
+type gestureRecognizer struct {
+	cellWidth  float32
+	cellHeight float32
+
+	start    map[int32]touchPoint
+	current  map[int32]touchPoint
+	order    []int32
+	downTime uint32
+	fingers  int
+	moved    bool
+	done     bool
+
+	// seq identifies the current gesture so stale long-press timers can be
+	// ignored.
+	seq int
+}
+
+// newGestureRecognizer creates a gestureRecognizer that reports positions in
+// cells of the given size.
+func newGestureRecognizer(cellWidth, cellHeight float32) *gestureRecognizer {
+	return &gestureRecognizer{
+		cellWidth:  cellWidth,
+		cellHeight: cellHeight,
+		start:      make(map[int32]touchPoint),
+		current:    make(map[int32]touchPoint),
+	}
+}
+
+// down records a finger going down and returns the sequence number of the
+// gesture it belongs to.
+func (g *gestureRecognizer) down(id int32, x, y float32, ms uint32) int {
+	if len(g.current) == 0 {
+		g.start = make(map[int32]touchPoint)
+		g.order = g.order[:0]
+		g.downTime = ms
+		g.fingers = 0
+		g.moved = false
+		g.done = false
+		g.seq++
+	}
+
+	pt := touchPoint{x: x, y: y}
+	g.start[id] = pt
+	g.current[id] = pt
+	g.order = append(g.order, id)
+	if len(g.current) > g.fingers {
+		g.fingers = len(g.current)
+	}
+	return g.seq
+}
+
+// motion records a finger moving.
+func (g *gestureRecognizer) motion(id int32, x, y float32) {
+	if _, ok := g.current[id]; !ok {
+		return
+	}
+	pt := touchPoint{x: x, y: y}
+	g.current[id] = pt
+	if g.start[id].distance(pt) > tapSlop {
+		g.moved = true
+	}
+}
+
+// up records a finger being lifted at the given event time in milliseconds
+// and returns the recognized gesture, if any.
+func (g *gestureRecognizer) up(id int32, ms uint32) *GestureMsg {
+	if _, ok := g.current[id]; !ok {
+		return nil
+	}
+
+	var gesture *GestureMsg
+	if !g.done {
+		g.done = true
+		switch g.fingers {
+		case 1:
+			held := time.Duration(ms-g.downTime) * time.Millisecond
+			if !g.moved && held < longPressDelay {
+				gesture = g.gestureAt(GestureTap, g.start[id])
+			}
+		case 2:
+			gesture = g.twoFingerGesture()
+		}
+	}
+
+	delete(g.current, id)
+	return gesture
+}
+
+// twoFingerGesture decides between a pinch and a swipe once the first of two
+// fingers is lifted.
+func (g *gestureRecognizer) twoFingerGesture() *GestureMsg {
+	if len(g.order) < 2 || len(g.current) < 2 {
+		return nil
+	}
+	ids := g.order[:2]
+	if _, ok := g.current[ids[0]]; !ok {
+		return nil
+	}
+	if _, ok := g.current[ids[1]]; !ok {
+		return nil
+	}
+
+	startCenter := centroid(g.start, ids)
+	endCenter := centroid(g.current, ids)
+
+	startDist := g.start[ids[0]].distance(g.start[ids[1]])
+	endDist := g.current[ids[0]].distance(g.current[ids[1]])
+	if startDist > 0 {
+		scale := endDist / startDist
+		if math.Abs(scale-1) >= pinchMinChange {
+			gesture := g.gestureAt(GesturePinch, startCenter)
+			gesture.Scale = scale
+			return gesture
+		}
+	}
+
+	if startCenter.distance(endCenter) >= swipeMinDistance {
+		gesture := g.gestureAt(GestureSwipe, startCenter)
+		gesture.DX = int((endCenter.x - startCenter.x) / g.cellWidth)
+		gesture.DY = int((endCenter.y - startCenter.y) / g.cellHeight)
+		return gesture
+	}
+	return nil
+}
+
+// longPress returns a long-press gesture if the gesture with the given
+// sequence number is still a single finger held in place.
+func (g *gestureRecognizer) longPress(seq int) *GestureMsg {
+	if seq != g.seq || g.done || g.fingers != 1 || g.moved || len(g.current) != 1 {
+		return nil
+	}
+	g.done = true
+	return g.gestureAt(GestureLongPress, g.start[g.order[0]])
+}
+
+// cancel abandons the current gesture.
+func (g *gestureRecognizer) cancel() {
+	g.current = make(map[int32]touchPoint)
+	g.done = true
+}
+
+// gestureAt creates a gesture message located at the given pixel position.
+func (g *gestureRecognizer) gestureAt(typ GestureType, pt touchPoint) *GestureMsg {
+	return &GestureMsg{
+		Type:    typ,
+		X:       int(pt.x / g.cellWidth),
+		Y:       int(pt.y / g.cellHeight),
+		Scale:   1,
+		Fingers: g.fingers,
+	}
+}
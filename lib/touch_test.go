@@ -0,0 +1,108 @@
+package lib
+
+import "testing"
+
+func TestGestureRecognizer_Tap(t *testing.T) {
+	g := newGestureRecognizer(10, 20)
+	g.down(1, 55, 45, 1000)
+	g.motion(1, 58, 47)
+
+	gesture := g.up(1, 1100)
+	if gesture == nil {
+		t.Fatal("expected a tap gesture")
+	}
+	if gesture.Type != GestureTap {
+		t.Errorf("gesture type = %v, want GestureTap", gesture.Type)
+	}
+	if gesture.X != 5 || gesture.Y != 2 {
+		t.Errorf("tap position = (%d, %d), want (5, 2)", gesture.X, gesture.Y)
+	}
+}
+
+func TestGestureRecognizer_MovedTouchIsNotTap(t *testing.T) {
+	g := newGestureRecognizer(10, 20)
+	g.down(1, 50, 50, 1000)
+	g.motion(1, 100, 50)
+
+	if gesture := g.up(1, 1100); gesture != nil {
+		t.Errorf("expected no gesture, got %v", gesture)
+	}
+}
+
+func TestGestureRecognizer_LongPress(t *testing.T) {
+	g := newGestureRecognizer(10, 20)
+	seq := g.down(1, 50, 50, 1000)
+
+	gesture := g.longPress(seq)
+	if gesture == nil || gesture.Type != GestureLongPress {
+		t.Fatalf("expected a long press gesture, got %v", gesture)
+	}
+
+	// Lifting the finger after a long press is not also a tap
+	if gesture := g.up(1, 1600); gesture != nil {
+		t.Errorf("expected no gesture on release, got %v", gesture)
+	}
+}
+
+func TestGestureRecognizer_StaleLongPress(t *testing.T) {
+	g := newGestureRecognizer(10, 20)
+	seq := g.down(1, 50, 50, 1000)
+	g.up(1, 1100)
+	g.down(2, 50, 50, 1200)
+
+	if gesture := g.longPress(seq); gesture != nil {
+		t.Errorf("expected stale long press to be ignored, got %v", gesture)
+	}
+}
+
+func TestGestureRecognizer_Swipe(t *testing.T) {
+	g := newGestureRecognizer(10, 20)
+	g.down(1, 100, 100, 1000)
+	g.down(2, 140, 100, 1010)
+	g.motion(1, 100, 200)
+	g.motion(2, 140, 200)
+
+	gesture := g.up(1, 1200)
+	if gesture == nil || gesture.Type != GestureSwipe {
+		t.Fatalf("expected a swipe gesture, got %v", gesture)
+	}
+	if gesture.DX != 0 || gesture.DY != 5 {
+		t.Errorf("swipe distance = (%d, %d), want (0, 5)", gesture.DX, gesture.DY)
+	}
+	if gesture.Fingers != 2 {
+		t.Errorf("swipe fingers = %d, want 2", gesture.Fingers)
+	}
+
+	if gesture := g.up(2, 1210); gesture != nil {
+		t.Errorf("expected no gesture for second finger, got %v", gesture)
+	}
+}
+
+func TestGestureRecognizer_Pinch(t *testing.T) {
+	g := newGestureRecognizer(10, 20)
+	g.down(1, 100, 100, 1000)
+	g.down(2, 200, 100, 1010)
+	g.motion(1, 50, 100)
+	g.motion(2, 250, 100)
+
+	gesture := g.up(2, 1200)
+	if gesture == nil || gesture.Type != GesturePinch {
+		t.Fatalf("expected a pinch gesture, got %v", gesture)
+	}
+	if gesture.Scale < 1.99 || gesture.Scale > 2.01 {
+		t.Errorf("pinch scale = %v, want 2", gesture.Scale)
+	}
+}
+
+func TestGestureRecognizer_Cancel(t *testing.T) {
+	g := newGestureRecognizer(10, 20)
+	seq := g.down(1, 50, 50, 1000)
+	g.cancel()
+
+	if gesture := g.longPress(seq); gesture != nil {
+		t.Errorf("expected no long press after cancel, got %v", gesture)
+	}
+	if gesture := g.up(1, 1100); gesture != nil {
+		t.Errorf("expected no tap after cancel, got %v", gesture)
+	}
+}
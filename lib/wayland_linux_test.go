@@ -0,0 +1,130 @@
+package lib
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/neurlang/wayland/window"
+	"github.com/neurlang/wayland/wl"
+)
+
+// fakeCompositor is the server end of a Wayland connection. It reads the
+// requests of the client and sends it events.
+type fakeCompositor struct {
+	t    *testing.T
+	conn *net.UnixConn
+}
+
+// wlRequest is a request read by the fake compositor.
+type wlRequest struct {
+	object wl.ProxyId
+	opcode uint16
+	args   []byte
+}
+
+// newFakeCompositor connects a display to a fake compositor.
+func newFakeCompositor(t *testing.T) (*window.Display, *fakeCompositor) {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("XDG_RUNTIME_DIR", dir)
+
+	ln, err := net.ListenUnix("unix", &net.UnixAddr{Name: filepath.Join(dir, "wayland-test"), Net: "unix"})
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+
+	display, err := wl.Connect("wayland-test")
+	if err != nil {
+		t.Fatalf("wl.Connect() error = %v", err)
+	}
+	conn, err := ln.AcceptUnix()
+	if err != nil {
+		t.Fatalf("accept: %v", err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		_ = display.Context().Close()
+	})
+	return &window.Display{Display: display}, &fakeCompositor{t: t, conn: conn}
+}
+
+// next reads the next request of the client.
+func (c *fakeCompositor) next() wlRequest {
+	c.t.Helper()
+	_ = c.conn.SetReadDeadline(time.Now().Add(time.Second))
+
+	var header [8]byte
+	if _, err := io.ReadFull(c.conn, header[:]); err != nil {
+		c.t.Fatalf("reading request: %v", err)
+	}
+	word := binary.NativeEndian.Uint32(header[4:])
+	req := wlRequest{
+		object: wl.ProxyId(binary.NativeEndian.Uint32(header[:4])),
+		opcode: uint16(word),
+		args:   make([]byte, word>>16-8),
+	}
+	if _, err := io.ReadFull(c.conn, req.args); err != nil {
+		c.t.Fatalf("reading request arguments: %v", err)
+	}
+	return req
+}
+
+// expect reads the next request and fails the test unless it is the
+// request of a proxy with the opcode.
+func (c *fakeCompositor) expect(proxy wl.Proxy, opcode uint16) wlRequest {
+	c.t.Helper()
+	req := c.next()
+	if req.object != proxy.Id() || req.opcode != opcode {
+		c.t.Fatalf("request %d of object %d, want request %d of object %d",
+			req.opcode, req.object, opcode, proxy.Id())
+	}
+	return req
+}
+
+// skip reads n requests without looking at them.
+func (c *fakeCompositor) skip(n int) {
+	c.t.Helper()
+	for i := 0; i < n; i++ {
+		c.next()
+	}
+}
+
+// send sends an event to a proxy of the client, with file descriptors.
+func (c *fakeCompositor) send(proxy wl.Proxy, opcode uint16, args []byte, fds ...int) {
+	c.t.Helper()
+	msg := make([]byte, 8, 8+len(args))
+	binary.NativeEndian.PutUint32(msg, uint32(proxy.Id()))
+	binary.NativeEndian.PutUint32(msg[4:], uint32(8+len(args))<<16|uint32(opcode))
+	msg = append(msg, args...)
+
+	var oob []byte
+	if len(fds) > 0 {
+		oob = syscall.UnixRights(fds...)
+	}
+	if _, _, err := c.conn.WriteMsgUnix(msg, oob, nil); err != nil {
+		c.t.Fatalf("sending event: %v", err)
+	}
+}
+
+// wlString encodes a string argument.
+func wlString(s string) []byte {
+	size := len(s) + 1
+	buf := make([]byte, 4+(size+3)&^3)
+	binary.NativeEndian.PutUint32(buf, uint32(size))
+	copy(buf[4:], s)
+	return buf
+}
+
+// dispatch reads and handles one event on the client.
+func dispatch(t *testing.T, display *window.Display) {
+	t.Helper()
+	if err := display.Display.Context().Run(); err != nil {
+		t.Fatalf("dispatching event: %v", err)
+	}
+}
@@ -0,0 +1,203 @@
+package lib
+
+// setWindowTitleMsg is an internal message that changes the window title.
+type setWindowTitleMsg struct {
+	title string
+}
+
+// setWindowSizeMsg is an internal message that resizes the window to a
+// number of cells.
+type setWindowSizeMsg struct {
+	cols int
+	rows int
+}
+
+// setSizeLimitMsg is an internal message that sets the minimum or maximum
+// window size in cells. Zero removes the limit.
+type setSizeLimitMsg struct {
+	max  bool
+	cols int
+	rows int
+}
+
+// toggleFullscreenMsg is an internal message that switches fullscreen on or
+// off.
+type toggleFullscreenMsg struct{}
+
+// maximizeMsg is an internal message that maximizes the window.
+type maximizeMsg struct{}
+
+// restoreMsg is an internal message that leaves fullscreen and unmaximizes
+// the window.
+type restoreMsg struct{}
+
+// minimizeMsg is an internal message that minimizes the window.
+type minimizeMsg struct{}
+
+// SetWindowTitle returns a command that changes the window title.
+func SetWindowTitle(title string) Cmd {
+	return func() Msg {
+		return setWindowTitleMsg{title: title}
+	}
+}
+
+// SetWindowSize returns a command that resizes the window to fit the given
+// number of columns and rows of cells.
+func SetWindowSize(cols, rows int) Cmd {
+	return func() Msg {
+		return setWindowSizeMsg{cols: cols, rows: rows}
+	}
+}
+
+// SetMinSize returns a command that keeps the window at least the given
+// number of columns and rows of cells. Zero removes the limit.
+func SetMinSize(cols, rows int) Cmd {
+	return func() Msg {
+		return setSizeLimitMsg{cols: cols, rows: rows}
+	}
+}
+
+// SetMaxSize returns a command that keeps the window at most the given
+// number of columns and rows of cells. Zero removes the limit.
+func SetMaxSize(cols, rows int) Cmd {
+	return func() Msg {
+		return setSizeLimitMsg{max: true, cols: cols, rows: rows}
+	}
+}
+
+// ToggleFullscreen switches the window between fullscreen and normal.
+func ToggleFullscreen() Msg {
+	return toggleFullscreenMsg{}
+}
+
+// Maximize maximizes the window.
+func Maximize() Msg {
+	return maximizeMsg{}
+}
+
+// Restore leaves fullscreen and unmaximizes the window.
+func Restore() Msg {
+	return restoreMsg{}
+}
+
+// Minimize minimizes the window.
+func Minimize() Msg {
+	return minimizeMsg{}
+}
+
+// handleWindowMsg applies internal window management messages. It reports
+// whether the message was consumed. Must be called with p.mu held.
+func (p *Program) handleWindowMsg(msg Msg) bool {
+	switch m := msg.(type) {
+	case setWindowTitleMsg:
+		Debug("Window title changed: %q", m.title)
+		p.options.WindowTitle = m.title
+		if p.ownsWindow() {
+			p.window.SetTitle(m.title)
+		}
+	case setWindowSizeMsg:
+		if m.cols <= 0 || m.rows <= 0 {
+			Warn("Ignoring window size %dx%d cells", m.cols, m.rows)
+			return true
+		}
+		cols, rows := p.clampCells(m.cols, m.rows)
+		Debug("Resizing window to %dx%d cells", cols, rows)
+		p.resizeToCells(cols, rows)
+	case setSizeLimitMsg:
+		if m.max {
+			p.maxCols, p.maxRows = m.cols, m.rows
+		} else {
+			p.minCols, p.minRows = m.cols, m.rows
+		}
+		Debug("Window size limits: min %dx%d, max %dx%d cells", p.minCols, p.minRows, p.maxCols, p.maxRows)
+		if p.windowWidth > 0 && p.windowHeight > 0 {
+			if cols, rows := p.clampCells(p.windowWidth, p.windowHeight); cols != p.windowWidth || rows != p.windowHeight {
+				p.resizeToCells(cols, rows)
+			}
+		}
+	case toggleFullscreenMsg:
+		// The window state changes once the window system confirms it
+		if p.ownsWindow() {
+			p.setFullscreen(!p.windowState.Fullscreen)
+		}
+	case maximizeMsg:
+		if p.ownsWindow() {
+			p.setMaximized(true)
+		}
+	case restoreMsg:
+		if p.ownsWindow() {
+			if p.windowState.Fullscreen {
+				p.setFullscreen(false)
+			}
+			if p.windowState.Maximized {
+				p.setMaximized(false)
+			}
+		}
+	case openWindowMsg:
+		p.openWindow(m)
+	case closeWindowMsg:
+		p.closeWindow(m.window)
+	case minimizeMsg:
+		if p.ownsWindow() {
+			if err := p.window.SetMinimized(); err != nil {
+				Warn("Failed to minimize window: %v", err)
+			}
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// ownsWindow reports whether the program may change its window. Embedded
+// programs leave the host's window alone.
+func (p *Program) ownsWindow() bool {
+	return p.window != nil && !p.embedded
+}
+
+// clampCells limits a window size in cells to the minimum and maximum
+// sizes. Must be called with p.mu held.
+func (p *Program) clampCells(cols, rows int) (int, int) {
+	if p.minCols > 0 && cols < p.minCols {
+		cols = p.minCols
+	}
+	if p.minRows > 0 && rows < p.minRows {
+		rows = p.minRows
+	}
+	if p.maxCols > 0 && cols > p.maxCols {
+		cols = p.maxCols
+	}
+	if p.maxRows > 0 && rows > p.maxRows {
+		rows = p.maxRows
+	}
+	return cols, rows
+}
+
+// resizeToCells asks the window system for a window that fits exactly the
+// given number of cells plus padding. It reports whether the backend
+// supports resizing.
+// Must be called with p.mu held.
+func (p *Program) resizeToCells(cols, rows int) bool {
+	if p.widget == nil || p.renderer == nil {
+		return false
+	}
+	width, height := windowSizeFor(cols, rows, p.renderer.CellWidth(), p.renderer.CellHeight(), p.options.Padding)
+	return p.requestWindowSize(width, height)
+}
+
+// setWindowState updates the window state and sends a WindowStateMsg if it
+// changed. Must be called with p.mu held.
+func (p *Program) setWindowState(update func(*WindowStateMsg)) {
+	state := p.windowState
+	update(&state)
+	if state == p.windowState {
+		return
+	}
+	p.windowState = state
+
+	// Sent without blocking because the caller may hold p.mu while the
+	// message channel is being drained
+	if p.queue.post(p.msgChan, state) {
+		p.scheduleRedraw()
+	}
+}
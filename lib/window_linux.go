@@ -0,0 +1,174 @@
+package lib
+
+import (
+	"errors"
+
+	"github.com/neurlang/wayland/wl"
+	"github.com/neurlang/wayland/xdg"
+	"github.com/neurlang/wayland/xkbcommon"
+)
+
+// keyPrintScreen is the keysym of the Print Screen key.
+const keyPrintScreen = xkbcommon.KeyPrint
+
+// requestWindowSize asks the compositor for a new window size in pixels.
+// It reports whether the backend supports resizing.
+func (p *Program) requestWindowSize(width, height int32) bool {
+	p.widget.ScheduleResize(width, height)
+	return true
+}
+
+// setFullscreen asks the compositor to switch fullscreen on or off. It
+// reports whether the request was made; the window state follows the
+// compositor's answer.
+func (p *Program) setFullscreen(fullscreen bool) bool {
+	if err := p.window.SetFullscreen(fullscreen); err != nil {
+		Warn("Failed to set fullscreen to %v: %v", fullscreen, err)
+		return false
+	}
+	return true
+}
+
+// setMaximized asks the compositor to maximize or restore the window. It
+// reports whether the request was made; the window state follows the
+// compositor's answer.
+func (p *Program) setMaximized(maximized bool) bool {
+	if err := p.window.SetMaximized(maximized); err != nil {
+		Warn("Failed to set maximized to %v: %v", maximized, err)
+		return false
+	}
+	return true
+}
+
+// windowStateWatcher follows the window state the compositor reports, which
+// also changes through its own controls and the backend's shortcuts.
+type windowStateWatcher struct {
+	p *Program
+}
+
+// HandleToplevelConfigure implements xdg.ToplevelConfigureHandler.
+func (w windowStateWatcher) HandleToplevelConfigure(ev xdg.ToplevelConfigureEvent) {
+	var state WindowStateMsg
+	for _, s := range ev.States {
+		switch s {
+		case xdg.ToplevelStateFullscreen:
+			state.Fullscreen = true
+		case xdg.ToplevelStateMaximized:
+			state.Maximized = true
+		case xdg.ToplevelStateActivated:
+			state.Activated = true
+		case xdg.ToplevelStateSuspended:
+			state.Suspended = true
+		}
+	}
+
+	w.p.mu.Lock()
+	w.p.setWindowState(func(s *WindowStateMsg) { *s = state })
+	w.p.mu.Unlock()
+}
+
+// watchState makes the window state follow the configure events of the
+// window's toplevel.
+func (p *Program) watchState() {
+	if p.toplevel == nil {
+		Warn("Window has no toplevel, its state is not reported")
+		return
+	}
+	p.toplevel.AddConfigureHandler(windowStateWatcher{p: p})
+}
+
+// secondaryWindows reports whether OpenWindow is supported.
+const secondaryWindows = true
+
+// windowCloser closes a secondary window when the user closes it.
+type windowCloser struct {
+	p *Program
+}
+
+// Close implements window.CloseHandler.
+func (c windowCloser) Close() {
+	c.p.requestClose()
+}
+
+// HandleToplevelClose implements xdg.ToplevelCloseHandler.
+func (c windowCloser) HandleToplevelClose(ev xdg.ToplevelCloseEvent) {
+	c.p.requestClose()
+}
+
+// watchClose makes closing a secondary window close only that window
+// instead of exiting the application. The backend calls the close handler
+// for its own shortcut, but exits the display loop when the compositor
+// asks any toplevel to close, as it does for the close button of the title
+// bar. That event is taken over on the window's toplevel.
+func (p *Program) watchClose() error {
+	if p.toplevel == nil {
+		return errors.New("window has no toplevel to watch for close requests")
+	}
+	closer := windowCloser{p: p}
+	p.window.SetCloseHandler(closer)
+	p.toplevel.RemoveCloseHandler(p.window)
+	p.toplevel.AddCloseHandler(closer)
+	return nil
+}
+
+// proxyLookup finds the objects of a Wayland connection by ID.
+type proxyLookup interface {
+	LookupProxy(id wl.ProxyId) wl.Proxy
+}
+
+// findToplevel finds the xdg toplevel that window.Create made for the
+// window, which the backend does not hand out. Windows are only created on
+// the display thread, so it is the newest toplevel of the connection: the
+// search goes down from the ID of a roundtrip callback created after it.
+func (p *Program) findToplevel() {
+	display := p.display.Display
+	cb, err := display.Sync()
+	if err != nil {
+		Warn("Failed to find the toplevel of the window: %v", err)
+		return
+	}
+	cb.AddDoneHandler(&wakeHandler{cb: cb, fn: func() {}})
+	p.toplevel = newestToplevel(display.Context(), cb.Id())
+}
+
+// newestToplevel returns the toplevel with the highest ID below before, or
+// nil if there is none.
+func newestToplevel(objects proxyLookup, before wl.ProxyId) *xdg.Toplevel {
+	for id := before; id > 1; id-- {
+		if toplevel, ok := objects.LookupProxy(id - 1).(*xdg.Toplevel); ok {
+			return toplevel
+		}
+	}
+	return nil
+}
+
+// wakeHandler runs a function when a roundtrip to the compositor completes.
+type wakeHandler struct {
+	cb *wl.Callback
+	fn func()
+}
+
+// HandleCallbackDone implements wl.CallbackDoneHandler.
+func (h *wakeHandler) HandleCallbackDone(ev wl.CallbackDoneEvent) {
+	h.cb.Unregister()
+	h.fn()
+}
+
+// wakeDisplay runs fn on the display thread after a roundtrip to the
+// compositor, which also wakes a display loop waiting for events. It can be
+// called from any goroutine.
+func (p *Program) wakeDisplay(fn func()) {
+	if p.display == nil {
+		return
+	}
+	display := p.display.Display
+	ctx := display.Context()
+
+	// Equivalent to display.Sync, with the handler added before the
+	// request is sent so the reply cannot be missed
+	cb := wl.NewCallback(ctx)
+	cb.AddDoneHandler(&wakeHandler{cb: cb, fn: fn})
+	if err := ctx.SendRequest(display, 0, cb); err != nil {
+		Warn("Failed to wake the display loop: %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+package lib
+
+import (
+	"testing"
+
+	"github.com/neurlang/wayland/window"
+	"github.com/neurlang/wayland/wl"
+	"github.com/neurlang/wayland/xdg"
+)
+
+// proxyMap is a proxyLookup over a fixed set of objects.
+type proxyMap map[wl.ProxyId]wl.Proxy
+
+func (m proxyMap) LookupProxy(id wl.ProxyId) wl.Proxy {
+	return m[id]
+}
+
+// TestNewestToplevel tests that the toplevel of a new window is found among
+// the objects of the connection.
+func TestNewestToplevel(t *testing.T) {
+	old, created := &xdg.Toplevel{}, &xdg.Toplevel{}
+	objects := proxyMap{
+		3: old,
+		7: &wl.Surface{},
+		8: created,
+		9: &wl.Buffer{},
+	}
+
+	if got := newestToplevel(objects, 11); got != created {
+		t.Errorf("newestToplevel found %p, want the newest toplevel %p", got, created)
+	}
+	if got := newestToplevel(objects, 8); got != old {
+		t.Errorf("newestToplevel below 8 found %p, want %p", got, old)
+	}
+	if got := newestToplevel(objects, 3); got != nil {
+		t.Errorf("newestToplevel below 3 found %p, want none", got)
+	}
+}
+
+// TestWindowCloser_ToplevelClose tests that closing a secondary window from
+// its title bar closes only that window.
+func TestWindowCloser_ToplevelClose(t *testing.T) {
+	p := NewProgram(nil)
+	child := newTestChild(p, p, 10)
+
+	windowCloser{p: child}.HandleToplevelClose(xdg.ToplevelCloseEvent{})
+
+	if p.ctx.Err() != nil {
+		t.Fatal("closing a secondary window cancelled the main program")
+	}
+	if msg := <-p.msgChan; msg != (closeWindowMsg{window: child}) {
+		t.Fatalf("main program received %v, want a close request", msg)
+	}
+	p.handleProgramMsg(closeWindowMsg{window: child})
+	if p.lookupWindow(10) != nil || child.ctx.Err() == nil {
+		t.Error("secondary window was not closed")
+	}
+	if msg := <-p.msgChan; msg != (WindowClosedMsg{ID: 10}) {
+		t.Errorf("opener received %v, want WindowClosedMsg for window 10", msg)
+	}
+}
+
+// TestWindowStateWatcher tests that the window state follows the states the
+// compositor reports, and is cleared when they are reported off.
+func TestWindowStateWatcher(t *testing.T) {
+	p := NewProgram(nil)
+	p.msgChan = make(chan Msg, 4)
+	watcher := windowStateWatcher{p: p}
+
+	watcher.HandleToplevelConfigure(xdg.ToplevelConfigureEvent{
+		States: []int32{xdg.ToplevelStateActivated, xdg.ToplevelStateMaximized},
+	})
+	if msg := <-p.msgChan; msg != (WindowStateMsg{Maximized: true, Activated: true}) {
+		t.Fatalf("state message = %v, want maximized and activated", msg)
+	}
+
+	watcher.HandleToplevelConfigure(xdg.ToplevelConfigureEvent{
+		States: []int32{xdg.ToplevelStateFullscreen, xdg.ToplevelStateTiledLeft},
+	})
+	if msg := <-p.msgChan; msg != (WindowStateMsg{Fullscreen: true}) {
+		t.Fatalf("state message = %v, want fullscreen only", msg)
+	}
+
+	watcher.HandleToplevelConfigure(xdg.ToplevelConfigureEvent{
+		States: []int32{xdg.ToplevelStateSuspended},
+	})
+	if msg := <-p.msgChan; msg != (WindowStateMsg{Suspended: true}) {
+		t.Fatalf("state message = %v, want suspended only", msg)
+	}
+
+	watcher.HandleToplevelConfigure(xdg.ToplevelConfigureEvent{})
+	if msg := <-p.msgChan; msg != (WindowStateMsg{}) {
+		t.Errorf("state message = %v, want no state", msg)
+	}
+	if p.windowState != (WindowStateMsg{}) {
+		t.Error("window state was not cleared")
+	}
+}
+
+// TestFocus_ToplevelActivated tests that keyboard focus does not change the
+// activated state of a window whose compositor reports it.
+func TestFocus_ToplevelActivated(t *testing.T) {
+	p := NewProgram(nil)
+	p.msgChan = make(chan Msg, 4)
+	p.toplevel = &xdg.Toplevel{}
+
+	p.Focus(nil, &window.Input{})
+	if p.windowState.Activated || len(p.msgChan) != 0 {
+		t.Error("keyboard focus changed the activated state reported by the compositor")
+	}
+}
@@ -0,0 +1,83 @@
+package lib
+
+import "testing"
+
+func TestWindowCommands(t *testing.T) {
+	tests := []struct {
+		name string
+		cmd  Cmd
+		want Msg
+	}{
+		{"SetWindowTitle", SetWindowTitle("Editor"), setWindowTitleMsg{title: "Editor"}},
+		{"SetWindowSize", SetWindowSize(80, 24), setWindowSizeMsg{cols: 80, rows: 24}},
+		{"SetMinSize", SetMinSize(20, 5), setSizeLimitMsg{cols: 20, rows: 5}},
+		{"SetMaxSize", SetMaxSize(120, 40), setSizeLimitMsg{max: true, cols: 120, rows: 40}},
+		{"ToggleFullscreen", ToggleFullscreen, toggleFullscreenMsg{}},
+		{"Maximize", Maximize, maximizeMsg{}},
+		{"Restore", Restore, restoreMsg{}},
+		{"Minimize", Minimize, minimizeMsg{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cmd(); got != tt.want {
+				t.Errorf("%s() = %#v, want %#v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWindowMessagesAreConsumed(t *testing.T) {
+	p := NewProgram(nil)
+
+	if !p.handleProgramMsg(SetWindowTitle("New Title")()) {
+		t.Error("SetWindowTitle message reached Update")
+	}
+	if p.options.WindowTitle != "New Title" {
+		t.Errorf("window title = %q, want %q", p.options.WindowTitle, "New Title")
+	}
+
+	if !p.handleProgramMsg(SetMinSize(20, 5)()) || !p.handleProgramMsg(SetMaxSize(100, 0)()) {
+		t.Error("size limit messages reached Update")
+	}
+	if p.handleProgramMsg(KeyMsg{}) {
+		t.Error("KeyMsg was consumed as a window message")
+	}
+}
+
+func TestClampCells(t *testing.T) {
+	p := NewProgram(nil)
+	p.handleProgramMsg(SetMinSize(20, 5)())
+	p.handleProgramMsg(SetMaxSize(100, 0)())
+
+	tests := []struct {
+		cols, rows         int
+		wantCols, wantRows int
+	}{
+		{80, 24, 80, 24},
+		{10, 2, 20, 5},
+		{200, 300, 100, 300},
+	}
+	for _, tt := range tests {
+		cols, rows := p.clampCells(tt.cols, tt.rows)
+		if cols != tt.wantCols || rows != tt.wantRows {
+			t.Errorf("clampCells(%d, %d) = (%d, %d), want (%d, %d)",
+				tt.cols, tt.rows, cols, rows, tt.wantCols, tt.wantRows)
+		}
+	}
+}
+
+func TestSetWindowState(t *testing.T) {
+	p := NewProgram(nil)
+	p.msgChan = make(chan Msg, 4)
+
+	p.setWindowState(func(s *WindowStateMsg) { s.Maximized = true })
+	p.setWindowState(func(s *WindowStateMsg) { s.Maximized = true })
+
+	if msg := <-p.msgChan; msg != (WindowStateMsg{Maximized: true}) {
+		t.Errorf("state message = %v, want maximized", msg)
+	}
+	if len(p.msgChan) != 0 {
+		t.Error("unchanged state was reported again")
+	}
+}
@@ -0,0 +1,63 @@
+package lib
+
+// keyPrintScreen is the keysym of the Print Screen key. The Windows backend
+// does not report it, so the value matches no key.
+const keyPrintScreen = 9999998
+
+// requestWindowSize asks for a new window size in pixels. It reports
+// whether the backend supports resizing; the Windows backend does not.
+func (p *Program) requestWindowSize(width, height int32) bool {
+	return false
+}
+
+// setFullscreen switches fullscreen on or off. It reports whether the
+// request was made. The Windows backend can only enter fullscreen, and
+// reports no window state, so the state follows the request.
+func (p *Program) setFullscreen(fullscreen bool) bool {
+	if !fullscreen {
+		Warn("Leaving fullscreen is not supported by the Windows backend")
+		return false
+	}
+	p.window.SetFullscreen(true)
+	p.setWindowState(func(s *WindowStateMsg) { s.Fullscreen = true })
+	return true
+}
+
+// setMaximized maximizes or restores the window. It reports whether the
+// request was made, and updates the window state as the backend reports
+// none.
+func (p *Program) setMaximized(maximized bool) bool {
+	if err := p.window.SeMaximized(maximized); err != nil {
+		Warn("Failed to set maximized to %v: %v", maximized, err)
+		return false
+	}
+	p.setWindowState(func(s *WindowStateMsg) { s.Maximized = maximized })
+	return true
+}
+
+// secondaryWindows reports whether OpenWindow is supported. The Windows
+// backend exits the application when any window is destroyed, so it is not.
+const secondaryWindows = false
+
+// findToplevel does nothing on Windows, where windows have no xdg
+// toplevel.
+func (p *Program) findToplevel() {}
+
+// watchState does nothing on Windows, where setFullscreen and setMaximized
+// update the window state.
+func (p *Program) watchState() {}
+
+// watchClose is never called on Windows, where secondary windows are not
+// supported.
+func (p *Program) watchClose() error {
+	return nil
+}
+
+// wakeDisplay runs fn on the next frame. The Windows backend has no way to
+// post work to its message loop, but redraws the window continuously, so
+// Quit, Kill and a cancelled parent context take effect within a frame.
+func (p *Program) wakeDisplay(fn func()) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.wakeups = append(p.wakeups, fn)
+}
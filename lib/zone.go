@@ -0,0 +1,230 @@
+package lib
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Zone is a region of the grid marked with Mark, used to find out which
+// part of the view the mouse is over.
+type Zone struct {
+	ID string
+	Region
+
+	// Pointer is the pointer shape shown over the zone, set with
+	// MarkWithPointer. PointerDefault leaves the shape unchanged.
+	Pointer PointerShape
+
+	// depth is the nesting level of the zone, 0 for outermost zones.
+	depth int
+}
+
+// contains reports whether the zone covers the given cell.
+func (z Zone) contains(x, y int) bool {
+	return x >= z.X && x < z.X+z.Width && y >= z.Y && y < z.Y+z.Height
+}
+
+// Zone markers are private CSI sequences: ESC[1;<id>z opens a zone,
+// ESC[2;<shape>;<id>z opens it with a pointer shape, and ESC[0;<id>z closes
+// it. The ID is written as the code points of its runes, so the markers
+// only contain parameters the parser already understands and no state is
+// kept between marking a view and parsing it.
+const (
+	zoneFinal       = 'z'
+	zoneClose       = 0
+	zoneOpen        = 1
+	zoneOpenPointer = 2
+)
+
+// zoneParams encodes a zone ID as marker parameters.
+func zoneParams(id string) string {
+	var b strings.Builder
+	for i, r := range id {
+		if i > 0 {
+			b.WriteByte(';')
+		}
+		b.WriteString(strconv.Itoa(int(r)))
+	}
+	return b.String()
+}
+
+// zoneIDFromParams decodes a zone ID from marker parameters.
+func zoneIDFromParams(codes []int) string {
+	runes := make([]rune, len(codes))
+	for i, c := range codes {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
+
+// Mark wraps s in zero-width markers that make it a zone with the given ID.
+// When the view is rendered, the cells s occupies are recorded, and mouse
+// events over them list id in MouseMsg.Zones. Zones may be nested.
+//
+// The markers are escape sequences, so widths of marked strings must be
+// measured with ANSI-aware functions.
+func Mark(id, s string) string {
+	return MarkWithPointer(id, PointerDefault, s)
+}
+
+// MarkWithPointer is like Mark, and also shows the given pointer shape while
+// the pointer is over the zone, such as PointerHand for links or
+// PointerText for editable text.
+func MarkWithPointer(id string, shape PointerShape, s string) string {
+	if id == "" {
+		return s
+	}
+	encoded := zoneParams(id)
+	open := strconv.Itoa(zoneOpen) + ";" + encoded
+	if shape != PointerDefault {
+		open = strconv.Itoa(zoneOpenPointer) + ";" + strconv.Itoa(int(shape)) + ";" + encoded
+	}
+	return "\x1b[" + open + string(zoneFinal) +
+		s +
+		"\x1b[" + strconv.Itoa(zoneClose) + ";" + encoded + string(zoneFinal)
+}
+
+// ZoneAt returns the IDs of the zones covering the given cell, innermost
+// first.
+func (tg *TerminalGrid) ZoneAt(x, y int) []string {
+	zones := tg.zonesAt(x, y)
+	if len(zones) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(zones))
+	for i, z := range zones {
+		ids[i] = z.ID
+	}
+	return ids
+}
+
+// zonesAt returns the zones covering the given cell, innermost first.
+func (tg *TerminalGrid) zonesAt(x, y int) []Zone {
+	var found []Zone
+	for _, z := range tg.Zones {
+		if z.contains(x, y) {
+			found = append(found, z)
+		}
+	}
+	if len(found) < 2 {
+		return found
+	}
+
+	// Deeper zones are nested inside shallower ones; keep marking order
+	// among zones at the same depth
+	zones := make([]Zone, 0, len(found))
+	for depth := maxZoneDepth(found); depth >= 0; depth-- {
+		for _, z := range found {
+			if z.depth == depth {
+				zones = append(zones, z)
+			}
+		}
+	}
+	return zones
+}
+
+// pointerAt returns the pointer shape of the innermost zone with a shape at
+// the given cell, or PointerDefault if there is none.
+func (tg *TerminalGrid) pointerAt(x, y int) PointerShape {
+	for _, z := range tg.zonesAt(x, y) {
+		if z.Pointer != PointerDefault {
+			return z.Pointer
+		}
+	}
+	return PointerDefault
+}
+
+// maxZoneDepth returns the deepest nesting level among zones.
+func maxZoneDepth(zones []Zone) int {
+	depth := 0
+	for _, z := range zones {
+		if z.depth > depth {
+			depth = z.depth
+		}
+	}
+	return depth
+}
+
+// openZone is a zone whose closing marker has not been parsed yet.
+type openZone struct {
+	id      string
+	pointer PointerShape
+	minX    int
+	minY    int
+	maxX    int
+	maxY    int
+	filled  bool
+}
+
+// handleZoneMarker opens or closes a zone for a zone marker sequence.
+func (p *ansiParser) handleZoneMarker(params string) {
+	codes := parseSGRParams(params)
+	if len(codes) < 2 {
+		return
+	}
+
+	switch codes[0] {
+	case zoneOpen:
+		p.zones = append(p.zones, openZone{id: zoneIDFromParams(codes[1:])})
+	case zoneOpenPointer:
+		if len(codes) < 3 {
+			return
+		}
+		p.zones = append(p.zones, openZone{
+			id:      zoneIDFromParams(codes[2:]),
+			pointer: PointerShape(codes[1]),
+		})
+	case zoneClose:
+		// Close the innermost open zone with this ID, along with any zones
+		// opened inside it and left unclosed
+		id := zoneIDFromParams(codes[1:])
+		for i := len(p.zones) - 1; i >= 0; i-- {
+			if p.zones[i].id == id {
+				for len(p.zones) > i {
+					p.closeZone()
+				}
+				return
+			}
+		}
+	}
+}
+
+// markZoneCell extends every open zone to cover the given cell.
+func (p *ansiParser) markZoneCell(x, y int) {
+	for i := range p.zones {
+		z := &p.zones[i]
+		if !z.filled {
+			z.minX, z.minY, z.maxX, z.maxY = x, y, x, y
+			z.filled = true
+			continue
+		}
+		z.minX = min(z.minX, x)
+		z.minY = min(z.minY, y)
+		z.maxX = max(z.maxX, x)
+		z.maxY = max(z.maxY, y)
+	}
+}
+
+// closeZone closes the innermost open zone and records it on the grid if it
+// covers any cells.
+func (p *ansiParser) closeZone() {
+	last := len(p.zones) - 1
+	z := p.zones[last]
+	p.zones = p.zones[:last]
+
+	if !z.filled {
+		return
+	}
+	p.grid.Zones = append(p.grid.Zones, Zone{
+		ID:      z.id,
+		Pointer: z.pointer,
+		Region: Region{
+			X:      z.minX,
+			Y:      z.minY,
+			Width:  z.maxX - z.minX + 1,
+			Height: z.maxY - z.minY + 1,
+		},
+		depth: last,
+	})
+}
@@ -0,0 +1,113 @@
+package lib
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMark_ZeroWidth(t *testing.T) {
+	grid := ParseANSI("a"+Mark("ok", "OK")+"b", 10, 1)
+
+	want := "aOKb"
+	for x, r := range want {
+		if got := grid.GetCell(x, 0).Rune; got != r {
+			t.Errorf("cell %d = %q, want %q", x, got, r)
+		}
+	}
+
+	if len(grid.Zones) != 1 {
+		t.Fatalf("zones = %v, want one zone", grid.Zones)
+	}
+	z := grid.Zones[0]
+	if z.ID != "ok" || z.Region != (Region{X: 1, Y: 0, Width: 2, Height: 1}) {
+		t.Errorf("zone = %+v, want ok at (1, 0) 2x1", z)
+	}
+}
+
+func TestMark_EmptyID(t *testing.T) {
+	if got := Mark("", "text"); got != "text" {
+		t.Errorf("Mark with empty ID = %q, want %q", got, "text")
+	}
+}
+
+// TestMark_IDInMarker tests that the zone ID travels in the markers, so a
+// view can be parsed without the process that marked it.
+func TestMark_IDInMarker(t *testing.T) {
+	grid := ParseANSI("\x1b[1;111;107zOK\x1b[0;111;107z", 10, 1)
+	if got := grid.ZoneAt(0, 0); !reflect.DeepEqual(got, []string{"ok"}) {
+		t.Errorf("ZoneAt(0, 0) = %v, want [ok]", got)
+	}
+
+	id := "row;ü 1"
+	grid = ParseANSI(MarkWithPointer(id, PointerHand, "x"), 10, 1)
+	if len(grid.Zones) != 1 || grid.Zones[0].ID != id || grid.Zones[0].Pointer != PointerHand {
+		t.Errorf("zones = %+v, want %q with PointerHand", grid.Zones, id)
+	}
+}
+
+func TestZoneAt_NestedInnermostFirst(t *testing.T) {
+	view := Mark("list", Mark("row1", "first")+"\n"+Mark("row2", "second"))
+	grid := ParseANSI(view, 20, 3)
+
+	tests := []struct {
+		x, y int
+		want []string
+	}{
+		{0, 0, []string{"row1", "list"}},
+		{5, 1, []string{"row2", "list"}},
+		{5, 0, []string{"list"}},
+		{0, 2, nil},
+	}
+
+	for _, tt := range tests {
+		if got := grid.ZoneAt(tt.x, tt.y); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ZoneAt(%d, %d) = %v, want %v", tt.x, tt.y, got, tt.want)
+		}
+	}
+}
+
+func TestZone_WithStyles(t *testing.T) {
+	view := "\x1b[1m" + Mark("bold", "\x1b[31mred\x1b[0m") + " plain"
+	grid := ParseANSI(view, 20, 1)
+
+	if got := grid.ZoneAt(2, 0); !reflect.DeepEqual(got, []string{"bold"}) {
+		t.Errorf("ZoneAt(2, 0) = %v, want [bold]", got)
+	}
+	if got := grid.ZoneAt(3, 0); got != nil {
+		t.Errorf("ZoneAt(3, 0) = %v, want none", got)
+	}
+	if cell := grid.GetCell(0, 0); !cell.Bold || cell.FgColor != ansi16Color(1) {
+		t.Errorf("styles inside zone were not applied: %+v", cell)
+	}
+}
+
+func TestZone_UnclosedExtendsToEnd(t *testing.T) {
+	full := Mark("open", "abc")
+	// Drop the closing marker
+	view := full[:len(full)-len(Mark("open", ""))/2]
+	grid := ParseANSI(view, 10, 1)
+
+	if got := grid.ZoneAt(2, 0); !reflect.DeepEqual(got, []string{"open"}) {
+		t.Errorf("ZoneAt(2, 0) = %v, want [open]", got)
+	}
+}
+
+func TestProgram_MouseMsgZones(t *testing.T) {
+	p := NewProgram(nil)
+	p.grid = ParseANSI(Mark("button", "[ OK ]"), 10, 1)
+
+	msg := p.withZones(MouseMsg{X: 2, Y: 0, Type: MousePress, Button: MouseButtonLeft})
+	mouseMsg := msg.(MouseMsg)
+	if !mouseMsg.InZone("button") {
+		t.Errorf("zones = %v, want button", mouseMsg.Zones)
+	}
+
+	msg = p.withZones(MouseMsg{X: 8, Y: 0})
+	if zones := msg.(MouseMsg).Zones; zones != nil {
+		t.Errorf("zones outside the button = %v, want none", zones)
+	}
+
+	if got := p.ZoneAt(0, 0); !reflect.DeepEqual(got, []string{"button"}) {
+		t.Errorf("Program.ZoneAt(0, 0) = %v, want [button]", got)
+	}
+}